@@ -0,0 +1,78 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const MaxBanAppealLength = 2000
+
+type BanStatusStore interface {
+	FetchActiveBan(uuid []byte) (defs.Ban, error)
+}
+
+// BanStatus returns the account's active ban, or nil if it isn't banned.
+func BanStatus[T BanStatusStore](store T, uuid []byte) (*defs.BanStatus, error) {
+	ban, err := store.FetchActiveBan(uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &defs.BanStatus{
+		Reason:    ban.Reason,
+		Issued:    ban.Issued,
+		Expires:   ban.Expires,
+		Appealed:  ban.Appealed != "",
+		Automatic: ban.Issuer == "",
+	}, nil
+}
+
+type AppealBanStore interface {
+	AddBanAppeal(uuid []byte, appeal string) (bool, error)
+}
+
+// /account/banappeal - appeal the account's active ban
+func AppealBan[T AppealBanStore](store T, uuid []byte, appeal string) error {
+	if appeal == "" {
+		return apierror.ErrInvalidAppeal.Withf("missing appeal")
+	}
+
+	if len(appeal) > MaxBanAppealLength {
+		return apierror.ErrInvalidAppeal.Withf("appeal must be at most %d characters", MaxBanAppealLength)
+	}
+
+	ok, err := store.AddBanAppeal(uuid, appeal)
+	if err != nil {
+		return apierror.Internalf("failed to add appeal: %s", err)
+	}
+
+	if !ok {
+		return apierror.ErrNoBanToAppeal
+	}
+
+	return nil
+}
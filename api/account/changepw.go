@@ -19,7 +19,8 @@ package account
 
 import (
 	"crypto/rand"
-	"fmt"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 )
 
 // Interface for database operations needed for changing password.
@@ -30,23 +31,23 @@ type ChangePWStore interface {
 
 func ChangePW[T ChangePWStore](store T, uuid []byte, password string) error {
 	if len(password) < 6 {
-		return fmt.Errorf("invalid password")
+		return apierror.ErrInvalidPassword
 	}
 
 	salt := make([]byte, ArgonSaltSize)
 	_, err := rand.Read(salt)
 	if err != nil {
-		return fmt.Errorf("failed to generate salt: %s", err)
+		return apierror.Internalf("failed to generate salt: %s", err)
 	}
 
 	err = store.RemoveSessionsFromUUID(uuid)
 	if err != nil {
-		return fmt.Errorf("failed to remove sessions: %s", err)
+		return apierror.Internalf("failed to remove sessions: %s", err)
 	}
 
 	err = store.UpdateAccountPassword(uuid, deriveArgon2IDKey([]byte(password), salt), salt)
 	if err != nil {
-		return fmt.Errorf("failed to add account record: %s", err)
+		return apierror.Internalf("failed to add account record: %s", err)
 	}
 
 	return nil
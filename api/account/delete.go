@@ -0,0 +1,120 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+)
+
+// DeletionGracePeriod is how long a requested deletion waits before the
+// account is purged. The player can cancel it in the meantime.
+var DeletionGracePeriod = 30 * 24 * time.Hour
+
+// Interface for database operations needed for requesting account deletion.
+type RequestDeletionStore interface {
+	FetchUsernameFromUUID(uuid []byte) (string, error)
+	CheckPasswordStore
+	ScheduleDeletionStore
+}
+
+// /account/delete - schedule the account for deletion after the grace period.
+// The password is required again so a stolen session can't delete the account.
+func RequestDeletion[T RequestDeletionStore](store T, uuid []byte, password string) (time.Time, error) {
+	username, err := store.FetchUsernameFromUUID(uuid)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = checkPassword(store, username, password)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return ScheduleDeletion(store, uuid, DeletionGracePeriod)
+}
+
+type ScheduleDeletionStore interface {
+	ScheduleAccountDeletion(uuid []byte, scheduled time.Time) error
+	RemoveSessionsFromUUID(uuid []byte) error
+}
+
+// ScheduleDeletion schedules the account to be purged after grace and logs it
+// out everywhere.
+func ScheduleDeletion[T ScheduleDeletionStore](store T, uuid []byte, grace time.Duration) (time.Time, error) {
+	scheduled := time.Now().UTC().Add(grace)
+
+	err := store.ScheduleAccountDeletion(uuid, scheduled)
+	if err != nil {
+		return time.Time{}, apierror.Internalf("failed to schedule deletion: %s", err)
+	}
+
+	err = store.RemoveSessionsFromUUID(uuid)
+	if err != nil {
+		return time.Time{}, apierror.Internalf("failed to remove sessions: %s", err)
+	}
+
+	return scheduled, nil
+}
+
+type CancelDeletionStore interface {
+	CancelAccountDeletion(uuid []byte) (bool, error)
+}
+
+// /account/delete/cancel - cancel a pending deletion
+func CancelDeletion[T CancelDeletionStore](store T, uuid []byte) error {
+	ok, err := store.CancelAccountDeletion(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to cancel deletion: %s", err)
+	}
+
+	if !ok {
+		return apierror.ErrDeletionNotPending
+	}
+
+	return nil
+}
+
+type PurgeStore interface {
+	DeleteSystemSaveDataS3(uuid []byte) error
+	DeleteAccount(uuid []byte) error
+}
+
+// Purge permanently removes the account. System saves kept in S3 are not
+// covered by the database's cascading deletes, so they are removed first.
+func Purge[T PurgeStore](store T, uuid []byte) error {
+	if os.Getenv("S3_SYSTEM_BUCKET_NAME") != "" {
+		err := store.DeleteSystemSaveDataS3(uuid)
+		var nokey *types.NoSuchKey
+		if err != nil && !errors.As(err, &nokey) {
+			return apierror.Internalf("failed to delete system save data: %s", err)
+		}
+	}
+
+	err := store.DeleteAccount(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to delete account: %s", err)
+	}
+
+	return nil
+}
@@ -33,12 +33,20 @@ var (
 
 	DiscordSession *discordgo.Session
 	DiscordGuildID string
+
+	// DiscordRoleMap maps Discord guild role names to server roles. Role sync
+	// is disabled when it is empty.
+	DiscordRoleMap map[string]string
 )
 
+// ErrNotGuildMember is returned by FetchGuildRoleNames when the user is not a
+// member of the guild.
+var ErrNotGuildMember = errors.New("user is not a member of the guild")
+
 type DiscordProvider interface {
 	HandleDiscordCallback(w http.ResponseWriter, r *http.Request) (string, error)
 	RetrieveDiscordId(code string) (string, error)
-	IsUserDiscordAdmin(discordId string, discordGuildID string) (bool, error)
+	FetchGuildRoleNames(discordId string, discordGuildID string) ([]string, error)
 }
 
 type discordProvider struct{}
@@ -122,33 +130,35 @@ func (s *discordProvider) RetrieveDiscordId(code string) (string, error) {
 	return user.Id, nil
 }
 
-func (s *discordProvider) IsUserDiscordAdmin(discordId string, discordGuildID string) (bool, error) {
+// FetchGuildRoleNames returns the names of every role the user holds in the guild,
+// or ErrNotGuildMember if they are not in it.
+func (s *discordProvider) FetchGuildRoleNames(discordId string, discordGuildID string) ([]string, error) {
 	// fetch all roles from discord
 	roles, err := DiscordSession.GuildRoles(discordGuildID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	// fetch all roles from user
-	userRoles, err := DiscordSession.GuildMember(discordGuildID, discordId)
+	member, err := DiscordSession.GuildMember(discordGuildID, discordId)
 	if err != nil {
-		return false, err
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Message != nil && (restErr.Message.Code == discordgo.ErrCodeUnknownMember || restErr.Message.Code == discordgo.ErrCodeUnknownUser) {
+			return nil, ErrNotGuildMember
+		}
+
+		return nil, err
 	}
 
-	// check if user has a "Dev" or a "Division Heads" role
-	var hasRole bool
-	for _, role := range userRoles.Roles {
+	var names []string
+	for _, role := range member.Roles {
 		for _, guildRole := range roles {
-			if role == guildRole.ID && (guildRole.Name == "Dev" || guildRole.Name == "Division Heads" || guildRole.Name == "Helper") {
-				hasRole = true
+			if role == guildRole.ID {
+				names = append(names, guildRole.Name)
 				break
 			}
 		}
 	}
 
-	if !hasRole {
-		return false, nil
-	}
-
-	return true, nil
+	return names, nil
 }
@@ -0,0 +1,148 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Interface for database operations needed for exporting account data.
+type ExportStore interface {
+	FetchAccountDetails(uuid []byte) (defs.AccountDetails, error)
+	FetchAccountStats(uuid []byte) (map[string]int, error)
+	FetchAccountDailyRuns(uuid []byte) ([]defs.AccountDailyRun, error)
+	FetchBans(target []byte, activeOnly bool, page, pageSize int) (defs.BanPage, error)
+	FetchUsernameHistory(uuid []byte) ([]defs.UsernameChange, error)
+	FetchSessionHistory(uuid []byte, page, pageSize int) (defs.SessionHistoryPage, error)
+	FetchSessionHistoryRun(uuid []byte, id int64) (defs.SessionHistoryData, error)
+	ReadSessionSaveData(uuid []byte, slot int) (defs.SessionSaveData, error)
+	savedata.GetSystemStore
+}
+
+// /account/export - write a zip archive of all data held about the account to w
+func Export[T ExportStore](store T, uuid []byte, w io.Writer) error {
+	details, err := store.FetchAccountDetails(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to fetch account: %s", err)
+	}
+
+	stats, err := store.FetchAccountStats(uuid)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return apierror.Internalf("failed to fetch account stats: %s", err)
+	}
+
+	dailyRuns, err := store.FetchAccountDailyRuns(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to fetch daily runs: %s", err)
+	}
+
+	bans, err := store.FetchBans(uuid, false, 1, 1000)
+	if err != nil {
+		return apierror.Internalf("failed to fetch bans: %s", err)
+	}
+
+	usernameHistory, err := store.FetchUsernameHistory(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to fetch username history: %s", err)
+	}
+
+	files := map[string]any{
+		"account.json":         details,
+		"stats.json":           stats,
+		"dailyRuns.json":       dailyRuns,
+		"bans.json":            bans.Bans,
+		"usernameHistory.json": usernameHistory,
+	}
+
+	// the run history is listed in history.json, with each archived run in full
+	// under history/
+	history := []defs.SessionHistoryEntry{}
+	for page, pageCount := 1, 1; page <= pageCount; page++ {
+		runs, err := store.FetchSessionHistory(uuid, page, savedata.HistoryMaxPageSize)
+		if err != nil {
+			return apierror.Internalf("failed to fetch run history: %s", err)
+		}
+
+		history = append(history, runs.Runs...)
+		pageCount = runs.PageCount
+	}
+
+	files["history.json"] = history
+
+	for _, run := range history {
+		data, err := store.FetchSessionHistoryRun(uuid, run.Id)
+		if err != nil {
+			// pruned since it was listed
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			return apierror.Internalf("failed to fetch archived run %d: %s", run.Id, err)
+		}
+
+		files[fmt.Sprintf("history/run%d.json", run.Id)] = data
+	}
+
+	for slot := range defs.SessionSlotCount {
+		session, err := store.ReadSessionSaveData(uuid, slot)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			return apierror.Internalf("failed to read session slot %d: %s", slot, err)
+		}
+
+		files[fmt.Sprintf("sessions/slot%d.json", slot)] = session
+	}
+
+	system, err := savedata.GetSystem(store, uuid)
+	if err != nil {
+		if !errors.Is(err, savedata.ErrSaveNotExist) {
+			return apierror.Internalf("failed to read system save data: %s", err)
+		}
+	} else {
+		files["system.json"] = system
+	}
+
+	zw := zip.NewWriter(w)
+	for name, data := range files {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "\t")
+		err = encoder.Encode(data)
+		if err != nil {
+			return apierror.Internalf("failed to encode %s: %s", name, err)
+		}
+	}
+
+	return zw.Close()
+}
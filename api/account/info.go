@@ -17,27 +17,60 @@
 
 package account
 
+import (
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
 type InfoResponse struct {
-	Username        string `json:"username"`
-	DiscordId       string `json:"discordId"`
-	GoogleId        string `json:"googleId"`
-	LastSessionSlot int    `json:"lastSessionSlot"`
-	HasAdminRole    bool   `json:"hasAdminRole"`
+	Username        string            `json:"username"`
+	DiscordId       string            `json:"discordId"`
+	GoogleId        string            `json:"googleId"`
+	LastSessionSlot int               `json:"lastSessionSlot"`
+	HasAdminRole    bool              `json:"hasAdminRole"`
+	Permissions     []defs.Permission `json:"permissions"`
+	Ban             *defs.BanStatus   `json:"ban,omitempty"`
+	DeletionDate    string            `json:"deletionDate,omitempty"`
 }
 
 type InfoStore interface {
 	GetLatestSessionSaveDataSlot(uuid []byte) (int, error)
+	FetchAccountPermissions(uuid []byte) ([]defs.Permission, error)
+	BanStatusStore
+	FetchAccountDeletion(uuid []byte) (string, error)
 }
 
 // /account/info - get account info
-func Info[T InfoStore](store T, username string, discordId string, googleId string, uuid []byte, hasAdminRole bool) (InfoResponse, error) {
+func Info[T InfoStore](store T, username string, discordId string, googleId string, uuid []byte) (InfoResponse, error) {
 	slot, _ := store.GetLatestSessionSaveDataSlot(uuid)
+
+	permissions, err := store.FetchAccountPermissions(uuid)
+	if err != nil {
+		return InfoResponse{}, err
+	}
+
+	if permissions == nil {
+		permissions = []defs.Permission{}
+	}
+
+	ban, err := BanStatus(store, uuid)
+	if err != nil {
+		return InfoResponse{}, err
+	}
+
+	deletionDate, err := store.FetchAccountDeletion(uuid)
+	if err != nil {
+		return InfoResponse{}, err
+	}
+
 	response := InfoResponse{
 		Username:        username,
 		LastSessionSlot: slot,
 		DiscordId:       discordId,
 		GoogleId:        googleId,
-		HasAdminRole:    hasAdminRole,
+		HasAdminRole:    len(permissions) > 0,
+		Permissions:     permissions,
+		Ban:             ban,
+		DeletionDate:    deletionDate,
 	}
 	return response, nil
 }
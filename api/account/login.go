@@ -23,7 +23,8 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"errors"
-	"fmt"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 )
 
 type LoginResponse GenericAuthResponse
@@ -39,33 +40,47 @@ func Login[T LoginStore](store T, username, password string) (LoginResponse, err
 	var response LoginResponse
 
 	if !isValidUsername(username) {
-		return response, fmt.Errorf("invalid username")
+		return response, apierror.ErrInvalidUsername
 	}
 
 	if len(password) < 6 {
-		return response, fmt.Errorf("invalid password")
+		return response, apierror.ErrInvalidPassword
 	}
 
-	key, salt, err := store.FetchAccountKeySaltFromUsername(username)
+	err := checkPassword(store, username, password)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return response, fmt.Errorf("account doesn't exist")
-		}
 		return response, err
 	}
 
-	if !bytes.Equal(key, deriveArgon2IDKey([]byte(password), salt)) {
-		return response, fmt.Errorf("password doesn't match")
-	}
-
 	response.Token, err = GenerateTokenForUsername(store, username)
 	if err != nil {
-		return response, fmt.Errorf("failed to generate token: %s", err)
+		return response, err
 	}
 
 	return response, nil
 }
 
+type CheckPasswordStore interface {
+	FetchAccountKeySaltFromUsername(username string) (key, salt []byte, err error)
+}
+
+// checkPassword verifies password against the stored key of the account.
+func checkPassword[T CheckPasswordStore](store T, username, password string) error {
+	key, salt, err := store.FetchAccountKeySaltFromUsername(username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apierror.ErrInvalidCredentials.Withf("account doesn't exist")
+		}
+		return err
+	}
+
+	if !bytes.Equal(key, deriveArgon2IDKey([]byte(password), salt)) {
+		return apierror.ErrInvalidCredentials.Withf("password doesn't match")
+	}
+
+	return nil
+}
+
 type GenerateTokenForUsernameStore interface {
 	AddAccountSession(username string, token []byte) error
 }
@@ -75,12 +90,12 @@ func GenerateTokenForUsername[T GenerateTokenForUsernameStore](store T, username
 	token := make([]byte, TokenSize)
 	_, err := rand.Read(token)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %s", err)
+		return "", apierror.Internalf("failed to generate token: %s", err)
 	}
 
 	err = store.AddAccountSession(username, token)
 	if err != nil {
-		return "", fmt.Errorf("failed to add account session")
+		return "", apierror.Internalf("failed to add account session: %s", err)
 	}
 
 	return base64.StdEncoding.EncodeToString(token), nil
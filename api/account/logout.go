@@ -20,7 +20,8 @@ package account
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 )
 
 // /account/logout - log out of account
@@ -34,10 +35,10 @@ func Logout[T LogoutStore](store T, token []byte) error {
 	err := store.RemoveSessionFromToken(token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("token not found")
+			return apierror.ErrInvalidToken.Withf("token not found")
 		}
 
-		return fmt.Errorf("failed to remove account session")
+		return apierror.Internalf("failed to remove account session: %s", err)
 	}
 
 	return nil
@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Interface for database operations needed for building a profile.
+type ProfileStore interface {
+	FetchAccountDetails(uuid []byte) (defs.AccountDetails, error)
+	FetchAccountStats(uuid []byte) (map[string]int, error)
+	FetchAccountDailySummary(uuid []byte) (int, int, *defs.AccountDailyRun, error)
+}
+
+// /account/stats - get the caller's own profile, private or not
+func Profile[T ProfileStore](store T, uuid []byte) (defs.Profile, error) {
+	details, err := store.FetchAccountDetails(uuid)
+	if err != nil {
+		return defs.Profile{}, err
+	}
+
+	return buildProfile(store, uuid, details)
+}
+
+// /profile/{username} - get another player's profile. viewer is nil for
+// anonymous requests. Banned accounts are treated as not existing.
+func PublicProfile[T ProfileStore](store T, target, viewer []byte) (defs.Profile, error) {
+	details, err := store.FetchAccountDetails(target)
+	if err != nil {
+		return defs.Profile{}, err
+	}
+
+	if details.Banned {
+		return defs.Profile{}, sql.ErrNoRows
+	}
+
+	if details.Private && string(target) != string(viewer) {
+		return defs.Profile{}, apierror.ErrProfilePrivate
+	}
+
+	return buildProfile(store, target, details)
+}
+
+func buildProfile[T ProfileStore](store T, uuid []byte, details defs.AccountDetails) (defs.Profile, error) {
+	profile := defs.Profile{
+		Username:   details.Username,
+		Registered: details.Registered,
+		Private:    details.Private,
+	}
+
+	stats, err := store.FetchAccountStats(uuid)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return profile, apierror.Internalf("failed to fetch account stats: %s", err)
+		}
+
+		// accounts that have never synced their system data have no stats yet
+		stats = map[string]int{}
+	}
+
+	profile.Stats = stats
+	profile.ClassicWins = stats["sessionsWon"]
+
+	profile.DailyRuns, profile.DailyWins, profile.DailyBest, err = store.FetchAccountDailySummary(uuid)
+	if err != nil {
+		return profile, apierror.Internalf("failed to fetch daily runs: %s", err)
+	}
+
+	return profile, nil
+}
+
+// Interface for database operations needed for changing profile visibility.
+type ProfileVisibilityStore interface {
+	UpdateAccountProfilePrivate(uuid []byte, private bool) error
+}
+
+// /account/profile - hide or show the caller's profile to other players
+func SetProfilePrivate[T ProfileVisibilityStore](store T, uuid []byte, private bool) error {
+	return store.UpdateAccountProfilePrivate(uuid, private)
+}
@@ -0,0 +1,71 @@
+package account
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+type mockProfileStore struct {
+	details defs.AccountDetails
+	stats   map[string]int
+}
+
+func (m *mockProfileStore) FetchAccountDetails(uuid []byte) (defs.AccountDetails, error) {
+	return m.details, nil
+}
+func (m *mockProfileStore) FetchAccountStats(uuid []byte) (map[string]int, error) {
+	if m.stats == nil {
+		return nil, sql.ErrNoRows
+	}
+	return m.stats, nil
+}
+func (m *mockProfileStore) FetchAccountDailySummary(uuid []byte) (int, int, *defs.AccountDailyRun, error) {
+	return 3, 1, &defs.AccountDailyRun{Score: 1200}, nil
+}
+
+func TestPublicProfile(t *testing.T) {
+	owner, other := []byte("owner"), []byte("other")
+
+	t.Run("Public", func(t *testing.T) {
+		store := &mockProfileStore{details: defs.AccountDetails{Username: "ash"}, stats: map[string]int{"sessionsWon": 4}}
+		profile, err := PublicProfile(store, owner, nil)
+		if err != nil {
+			t.Fatalf("expected success, got error: %v", err)
+		}
+		if profile.ClassicWins != 4 || profile.DailyWins != 1 || profile.DailyBest.Score != 1200 {
+			t.Errorf("unexpected profile: %+v", profile)
+		}
+	})
+	t.Run("NoStats", func(t *testing.T) {
+		store := &mockProfileStore{details: defs.AccountDetails{Username: "ash"}}
+		profile, err := PublicProfile(store, owner, nil)
+		if err != nil {
+			t.Fatalf("expected success, got error: %v", err)
+		}
+		if profile.Stats == nil {
+			t.Errorf("expected empty stats, got nil")
+		}
+	})
+	t.Run("Private", func(t *testing.T) {
+		store := &mockProfileStore{details: defs.AccountDetails{Username: "ash", Private: true}}
+		_, err := PublicProfile(store, owner, other)
+		if !errors.Is(err, apierror.ErrProfilePrivate) {
+			t.Errorf("expected private profile error, got: %v", err)
+		}
+		_, err = PublicProfile(store, owner, owner)
+		if err != nil {
+			t.Errorf("expected owner to see their private profile, got: %v", err)
+		}
+	})
+	t.Run("Banned", func(t *testing.T) {
+		store := &mockProfileStore{details: defs.AccountDetails{Username: "ash", Banned: true}}
+		_, err := PublicProfile(store, owner, nil)
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected banned account to be hidden, got: %v", err)
+		}
+	})
+}
@@ -19,7 +19,8 @@ package account
 
 import (
 	"crypto/rand"
-	"fmt"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 )
 
 // Interface for database operations needed for registration.
@@ -30,28 +31,32 @@ type RegisterStore interface {
 // /account/register - register account
 func Register[T RegisterStore](store T, username, password string) error {
 	if !isValidUsername(username) {
-		return fmt.Errorf("invalid username")
+		return apierror.ErrInvalidUsername
+	}
+
+	if isReservedUsername(username) {
+		return apierror.ErrUsernameNotAllowed
 	}
 
 	if len(password) < 6 {
-		return fmt.Errorf("invalid password")
+		return apierror.ErrInvalidPassword
 	}
 
 	uuid := make([]byte, UUIDSize)
 	_, err := rand.Read(uuid)
 	if err != nil {
-		return fmt.Errorf("failed to generate uuid: %s", err)
+		return apierror.Internalf("failed to generate uuid: %s", err)
 	}
 
 	salt := make([]byte, ArgonSaltSize)
 	_, err = rand.Read(salt)
 	if err != nil {
-		return fmt.Errorf("failed to generate salt: %s", err)
+		return apierror.Internalf("failed to generate salt: %s", err)
 	}
 
 	err = store.AddAccountRecord(uuid, username, deriveArgon2IDKey([]byte(password), salt), salt)
 	if err != nil {
-		return fmt.Errorf("failed to add account record: %s", err)
+		return apierror.Internalf("failed to add account record: %s", err)
 	}
 
 	return nil
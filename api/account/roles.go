@@ -0,0 +1,104 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// ParseDiscordRoleMap parses a comma separated list of `Discord Role:serverRole`
+// pairs, as read from the discordrolemap env var.
+func ParseDiscordRoleMap(value string) (map[string]string, error) {
+	roleMap := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		discordRole, role, ok := strings.Cut(pair, ":")
+		discordRole, role = strings.TrimSpace(discordRole), strings.TrimSpace(role)
+		if !ok || discordRole == "" || role == "" {
+			return nil, fmt.Errorf("invalid discord role mapping %q", pair)
+		}
+
+		roleMap[discordRole] = role
+	}
+
+	return roleMap, nil
+}
+
+type SyncDiscordRolesStore interface {
+	ReplaceAccountRolesFromSource(uuid []byte, source defs.RoleSource, roles []string) error
+}
+
+// SyncDiscordRoles replaces the account's Discord-sourced role grants with the
+// server roles mapped from the user's current guild roles. Manual grants are
+// never touched. A user who left the guild loses every Discord-sourced role;
+// if Discord can't be reached the grants are left as they are. It is a no-op
+// when Discord role sync is not configured.
+func SyncDiscordRoles[T SyncDiscordRolesStore](store T, uuid []byte, discordId string) error {
+	if len(DiscordRoleMap) == 0 || DiscordGuildID == "" {
+		return nil
+	}
+
+	var roles []string
+	if discordId != "" {
+		guildRoles, err := Discord.FetchGuildRoleNames(discordId, DiscordGuildID)
+		if err != nil && !errors.Is(err, ErrNotGuildMember) {
+			return apierror.Internalf("failed to fetch discord roles: %s", err)
+		}
+
+		for _, guildRole := range guildRoles {
+			if role, ok := DiscordRoleMap[guildRole]; ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	err := store.ReplaceAccountRolesFromSource(uuid, defs.RoleSourceDiscord, roles)
+	if err != nil {
+		return apierror.Internalf("failed to update discord roles: %s", err)
+	}
+
+	return nil
+}
+
+type GrantRoleStore interface {
+	RoleExists(role string) (bool, error)
+	GrantAccountRole(uuid []byte, role string, source defs.RoleSource) error
+}
+
+// GrantRole manually grants a role to an account.
+func GrantRole[T GrantRoleStore](store T, uuid []byte, role string) error {
+	exists, err := store.RoleExists(role)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return apierror.ErrRoleNotFound
+	}
+
+	return store.GrantAccountRole(uuid, role, defs.RoleSourceManual)
+}
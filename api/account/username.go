@@ -0,0 +1,223 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package account
+
+import (
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+)
+
+var (
+	// UsernameChangeCooldown is the minimum time between two username
+	// changes on the same account.
+	UsernameChangeCooldown = 30 * 24 * time.Hour
+
+	// UsernameDenyList holds the skeletons of reserved and disallowed names.
+	// A username is rejected if it, or a run of words in it, has one of them
+	// as its skeleton.
+	UsernameDenyList []string
+)
+
+// confusables maps characters that look like latin letters to the letter
+// they are mistaken for. Digits are included since they are allowed in
+// usernames and are the usual way around a plain deny-list.
+var confusables = map[rune]string{
+	'0': "o", '1': "l", '3': "e", '4': "a", '5': "s", '7': "t", '8': "b", '9': "g",
+	'i': "l",
+}
+
+// confusableSequences catches letter pairs that read as a single letter.
+var confusableSequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// usernameSkeleton reduces s to a canonical form so that names which look
+// alike compare equal, e.g. "Adm1n", "ADMIN" and "adm_in".
+func usernameSkeleton(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			continue
+		}
+
+		if replacement, ok := confusables[r]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return confusableSequences.Replace(b.String())
+}
+
+// usernameWords splits username into words at underscores and at the humps
+// of camel case, e.g. "TheADMIN_99" into "The", "ADMIN" and "99".
+func usernameWords(username string) []string {
+	var words []string
+	runes := []rune(username)
+
+	start := 0
+	for i, r := range runes {
+		if r == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+
+		if i > start && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || (unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// ParseUsernameDenyList parses a comma separated list of names, as read from
+// the usernamedenylist env var, into skeletons for UsernameDenyList.
+func ParseUsernameDenyList(value string) []string {
+	var denyList []string
+	for _, name := range strings.Split(value, ",") {
+		skeleton := usernameSkeleton(name)
+		if skeleton != "" {
+			denyList = append(denyList, skeleton)
+		}
+	}
+
+	return denyList
+}
+
+// isReservedUsername reports whether username, or a run of words in it,
+// resembles a name on the deny-list. Only whole words are compared so that
+// names merely containing a reserved one, like "Badminton", are allowed.
+// Digits around the words are ignored, as in "Admin99".
+func isReservedUsername(username string) bool {
+	words := usernameWords(username)
+	for i := range words {
+		for j := i + 1; j <= len(words); j++ {
+			run := strings.Join(words[i:j], "")
+			skeletons := []string{usernameSkeleton(run), usernameSkeleton(strings.TrimFunc(run, unicode.IsDigit))}
+			for _, denied := range UsernameDenyList {
+				if slices.Contains(skeletons, denied) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Interface for database operations needed for changing username.
+type ChangeUsernameStore interface {
+	FetchUsernameFromUUID(uuid []byte) (string, error)
+	FetchLastUsernameChange(uuid []byte) (string, error)
+	CheckUsernameExists(username string) (string, error)
+	UpdateAccountUsername(uuid []byte, username string) error
+	CopySystemSaveDataS3(fromUsername, toUsername string) error
+	DeleteSystemSaveDataS3ByUsername(username string) error
+}
+
+// /account/username - change username
+func ChangeUsername[T ChangeUsernameStore](store T, uuid []byte, username string) error {
+	if !isValidUsername(username) {
+		return apierror.ErrInvalidUsername
+	}
+
+	if isReservedUsername(username) {
+		return apierror.ErrUsernameNotAllowed
+	}
+
+	current, err := store.FetchUsernameFromUUID(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to fetch username: %s", err)
+	}
+
+	// only the capitalisation changes, which is always allowed
+	caseOnly := strings.EqualFold(current, username)
+	if current == username {
+		return apierror.ErrUsernameUnchanged
+	}
+
+	if !caseOnly {
+		lastChange, err := store.FetchLastUsernameChange(uuid)
+		if err != nil {
+			return apierror.Internalf("failed to fetch last username change: %s", err)
+		}
+
+		if lastChange != "" {
+			changed, err := time.Parse(time.DateTime, lastChange)
+			if err != nil {
+				return apierror.Internalf("failed to parse last username change: %s", err)
+			}
+
+			if next := changed.Add(UsernameChangeCooldown); time.Now().UTC().Before(next) {
+				return apierror.ErrUsernameCooldown.Withf("username can not be changed again until %s", next.Format(time.DateTime))
+			}
+		}
+
+		// lookups are case-insensitive, so this also catches names differing only in case
+		if _, err := store.CheckUsernameExists(username); err == nil {
+			return apierror.ErrUsernameTaken
+		}
+	}
+
+	// system saves in S3 are keyed by username, so they have to move with it.
+	// The copy is made first so the save is never missing under either name.
+	useS3 := os.Getenv("S3_SYSTEM_BUCKET_NAME") != ""
+	if useS3 {
+		err = store.CopySystemSaveDataS3(current, username)
+		var nokey *types.NoSuchKey
+		if err != nil && !errors.As(err, &nokey) {
+			return apierror.Internalf("failed to copy system save data: %s", err)
+		}
+	}
+
+	err = store.UpdateAccountUsername(uuid, username)
+	if err != nil {
+		if useS3 {
+			store.DeleteSystemSaveDataS3ByUsername(username)
+		}
+
+		return apierror.Internalf("failed to update username: %s", err)
+	}
+
+	if useS3 {
+		// the rename went through, so a leftover copy is only logged
+		err = store.DeleteSystemSaveDataS3ByUsername(current)
+		if err != nil {
+			log.Printf("failed to delete old system save data of %s: %s", current, err)
+		}
+	}
+
+	return nil
+}
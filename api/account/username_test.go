@@ -0,0 +1,124 @@
+package account
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockUsernameStore struct {
+	username   string
+	lastChange string
+	taken      map[string]bool
+	updated    string
+	deleteErr  error
+}
+
+func (m *mockUsernameStore) FetchUsernameFromUUID(uuid []byte) (string, error) {
+	return m.username, nil
+}
+func (m *mockUsernameStore) FetchLastUsernameChange(uuid []byte) (string, error) {
+	return m.lastChange, nil
+}
+func (m *mockUsernameStore) CheckUsernameExists(username string) (string, error) {
+	if m.taken[username] {
+		return username, nil
+	}
+	return "", sql.ErrNoRows
+}
+func (m *mockUsernameStore) UpdateAccountUsername(uuid []byte, username string) error {
+	m.updated = username
+	return nil
+}
+func (m *mockUsernameStore) CopySystemSaveDataS3(fromUsername, toUsername string) error {
+	return nil
+}
+func (m *mockUsernameStore) DeleteSystemSaveDataS3ByUsername(username string) error {
+	return m.deleteErr
+}
+
+func TestReservedUsername(t *testing.T) {
+	UsernameDenyList = ParseUsernameDenyList("admin, pagefault ,,staff")
+	defer func() { UsernameDenyList = nil }()
+
+	reserved := []string{"admin", "ADMIN", "Adm1n", "adm_in", "TheAdmin99", "PageFau1t", "Page_Fault", "Staff_Member", "5taff", "xX_Admin_Xx"}
+	for _, username := range reserved {
+		if !isReservedUsername(username) {
+			t.Errorf("expected %q to be reserved", username)
+		}
+	}
+
+	allowed := []string{"ash", "misty", "brock123", "adamant", "Badminton", "Gustaffson", "staffordshire"}
+	for _, username := range allowed {
+		if isReservedUsername(username) {
+			t.Errorf("expected %q to be allowed", username)
+		}
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash"}
+		err := ChangeUsername(store, nil, "red")
+		if err != nil {
+			t.Errorf("expected success, got error: %v", err)
+		}
+		if store.updated != "red" {
+			t.Errorf("expected username to be updated to red, got %q", store.updated)
+		}
+	})
+	t.Run("InvalidUsername", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash"}
+		err := ChangeUsername(store, nil, "red!")
+		if err == nil || err.Error() != "invalid username" {
+			t.Errorf("expected invalid username error, got: %v", err)
+		}
+	})
+	t.Run("Unchanged", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash"}
+		err := ChangeUsername(store, nil, "ash")
+		if err == nil {
+			t.Errorf("expected error for unchanged username, got nil")
+		}
+	})
+	t.Run("Taken", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash", taken: map[string]bool{"red": true}}
+		err := ChangeUsername(store, nil, "red")
+		if err == nil || err.Error() != "username is already taken" {
+			t.Errorf("expected username taken error, got: %v", err)
+		}
+	})
+	t.Run("Cooldown", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash", lastChange: time.Now().UTC().Add(-time.Hour).Format(time.DateTime)}
+		err := ChangeUsername(store, nil, "red")
+		if err == nil {
+			t.Errorf("expected cooldown error, got nil")
+		}
+	})
+	t.Run("CooldownElapsed", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash", lastChange: time.Now().UTC().Add(-UsernameChangeCooldown - time.Hour).Format(time.DateTime)}
+		err := ChangeUsername(store, nil, "red")
+		if err != nil {
+			t.Errorf("expected success after cooldown, got error: %v", err)
+		}
+	})
+	t.Run("OldSaveLeftBehind", func(t *testing.T) {
+		t.Setenv("S3_SYSTEM_BUCKET_NAME", "saves")
+		store := &mockUsernameStore{username: "ash", deleteErr: errors.New("access denied")}
+		err := ChangeUsername(store, nil, "red")
+		if err != nil {
+			t.Errorf("expected rename to succeed, got error: %v", err)
+		}
+		if store.updated != "red" {
+			t.Errorf("expected username to be updated to red, got %q", store.updated)
+		}
+	})
+	t.Run("CaseOnlyIgnoresCooldown", func(t *testing.T) {
+		store := &mockUsernameStore{username: "ash", lastChange: time.Now().UTC().Format(time.DateTime), taken: map[string]bool{"ash": true}}
+		err := ChangeUsername(store, nil, "Ash")
+		if err != nil {
+			t.Errorf("expected case-only change to succeed, got error: %v", err)
+		}
+	})
+}
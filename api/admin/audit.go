@@ -0,0 +1,79 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package admin
+
+import (
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	AuditLogDefaultPageSize = 50
+	AuditLogMaxPageSize     = 200
+)
+
+// Interface for database operations needed for recording admin actions.
+type RecordStore interface {
+	AddAuditLogEntry(actor []byte, action defs.AuditAction, target []byte, before, after, ip string) error
+}
+
+// Record appends an admin action to the audit log. target may be nil for
+// actions that don't concern a single account.
+func Record[T RecordStore](store T, actor []byte, action defs.AuditAction, target []byte, before, after, ip string) error {
+	err := store.AddAuditLogEntry(actor, action, target, before, after, ip)
+	if err != nil {
+		return apierror.Internalf("failed to add audit log entry: %s", err)
+	}
+
+	return nil
+}
+
+// Interface for database operations needed for fetching the audit log.
+type AuditLogStore interface {
+	FetchAuditLog(filter defs.AuditLogFilter) (defs.AuditLogPage, error)
+}
+
+// /admin/audit - fetch a page of the admin audit log, newest first
+func AuditLog[T AuditLogStore](store T, filter defs.AuditLogFilter) (defs.AuditLogPage, error) {
+	if filter.Page < 1 {
+		return defs.AuditLogPage{}, apierror.ErrInvalidPage
+	}
+
+	if filter.PageSize == 0 {
+		filter.PageSize = AuditLogDefaultPageSize
+	}
+
+	if filter.PageSize < 1 || filter.PageSize > AuditLogMaxPageSize {
+		return defs.AuditLogPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", AuditLogMaxPageSize)
+	}
+
+	for _, date := range []string{filter.From, filter.To} {
+		if date == "" {
+			continue
+		}
+
+		_, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return defs.AuditLogPage{}, apierror.ErrInvalidDate.Withf("invalid date %q: expected YYYY-MM-DD", date)
+		}
+	}
+
+	return store.FetchAuditLog(filter)
+}
@@ -0,0 +1,128 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	BansDefaultPageSize = 50
+	BansMaxPageSize     = 200
+)
+
+// Interface for database operations needed for banning an account.
+type BanStore interface {
+	AddAccountBan(uuid, issuer []byte, reason, evidence string, expires *time.Time) error
+}
+
+// /admin/account/ban - ban an account. A zero duration bans permanently.
+func Ban[T BanStore](store T, target, issuer []byte, reason, evidence string, duration time.Duration) error {
+	if reason == "" {
+		return apierror.ErrInvalidParameter.Withf("missing ban reason")
+	}
+
+	if duration < 0 {
+		return apierror.ErrInvalidParameter.Withf("invalid ban duration")
+	}
+
+	if evidence != "" {
+		link, err := url.Parse(evidence)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return apierror.ErrInvalidParameter.Withf("evidence must be an http(s) link")
+		}
+	}
+
+	var expires *time.Time
+	if duration > 0 {
+		expiry := time.Now().UTC().Add(duration)
+		expires = &expiry
+	}
+
+	err := store.AddAccountBan(target, issuer, reason, evidence, expires)
+	if err != nil {
+		return apierror.Internalf("failed to add ban: %s", err)
+	}
+
+	return nil
+}
+
+// Interface for database operations needed for lifting a ban.
+type UnbanStore interface {
+	FetchActiveBan(uuid []byte) (defs.Ban, error)
+	LiftAccountBans(uuid, liftedBy []byte, reason string) error
+	ClearLegacyBan(uuid []byte) (bool, error)
+}
+
+// /admin/account/unban - lift every active ban on an account. Accounts banned
+// before bans were recorded have no ban to lift, only their flag is cleared.
+// Returns sql.ErrNoRows if the account is not banned.
+func Unban[T UnbanStore](store T, target, liftedBy []byte, reason string) (defs.Ban, error) {
+	ban, err := store.FetchActiveBan(target)
+	if errors.Is(err, sql.ErrNoRows) {
+		cleared, err := store.ClearLegacyBan(target)
+		if err != nil {
+			return ban, apierror.Internalf("failed to lift ban: %s", err)
+		}
+
+		if !cleared {
+			return ban, sql.ErrNoRows
+		}
+
+		return ban, nil
+	}
+
+	if err != nil {
+		return ban, err
+	}
+
+	err = store.LiftAccountBans(target, liftedBy, reason)
+	if err != nil {
+		return ban, apierror.Internalf("failed to lift ban: %s", err)
+	}
+
+	return ban, nil
+}
+
+// Interface for database operations needed for listing bans.
+type BansStore interface {
+	FetchBans(target []byte, activeOnly bool, page, pageSize int) (defs.BanPage, error)
+}
+
+// /admin/bans - list bans, newest first
+func Bans[T BansStore](store T, target []byte, activeOnly bool, page, pageSize int) (defs.BanPage, error) {
+	if page < 1 {
+		return defs.BanPage{}, apierror.ErrInvalidPage
+	}
+
+	if pageSize == 0 {
+		pageSize = BansDefaultPageSize
+	}
+
+	if pageSize < 1 || pageSize > BansMaxPageSize {
+		return defs.BanPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", BansMaxPageSize)
+	}
+
+	return store.FetchBans(target, activeOnly, page, pageSize)
+}
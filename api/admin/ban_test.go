@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+type mockUnbanStore struct {
+	ban      *defs.Ban
+	flagged  bool
+	lifted   bool
+	clearErr error
+}
+
+func (m *mockUnbanStore) FetchActiveBan(uuid []byte) (defs.Ban, error) {
+	if m.ban == nil {
+		return defs.Ban{}, sql.ErrNoRows
+	}
+
+	return *m.ban, nil
+}
+
+func (m *mockUnbanStore) LiftAccountBans(uuid, liftedBy []byte, reason string) error {
+	m.ban = nil
+	m.flagged = false
+	m.lifted = true
+
+	return nil
+}
+
+func (m *mockUnbanStore) ClearLegacyBan(uuid []byte) (bool, error) {
+	if m.clearErr != nil {
+		return false, m.clearErr
+	}
+
+	cleared := m.flagged
+	m.flagged = false
+
+	return cleared, nil
+}
+
+func TestUnban(t *testing.T) {
+	t.Run("ActiveBan", func(t *testing.T) {
+		store := &mockUnbanStore{ban: &defs.Ban{Reason: "cheating"}, flagged: true}
+		ban, err := Unban(store, []byte("target"), []byte("admin"), "appeal accepted")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ban.Reason != "cheating" || !store.lifted || store.flagged {
+			t.Errorf("expected ban to be lifted, got %+v", store)
+		}
+	})
+	t.Run("LegacyBan", func(t *testing.T) {
+		store := &mockUnbanStore{flagged: true}
+		_, err := Unban(store, []byte("target"), []byte("admin"), "appeal accepted")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.flagged {
+			t.Errorf("expected legacy ban flag to be cleared")
+		}
+	})
+	t.Run("NotBanned", func(t *testing.T) {
+		store := &mockUnbanStore{}
+		_, err := Unban(store, []byte("target"), []byte("admin"), "")
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+	t.Run("ClearError", func(t *testing.T) {
+		store := &mockUnbanStore{flagged: true, clearErr: errors.New("connection refused")}
+		_, err := Unban(store, []byte("target"), []byte("admin"), "")
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected database error, got %v", err)
+		}
+	})
+}
+
+func TestBansPaging(t *testing.T) {
+	if _, err := Bans(&mockBansStore{}, nil, false, 0, 0); !errors.Is(err, apierror.ErrInvalidPage) {
+		t.Errorf("expected an invalid page error, got %v", err)
+	}
+	if _, err := Bans(&mockBansStore{}, nil, false, 1, BansMaxPageSize+1); !errors.Is(err, apierror.ErrInvalidPageSize) {
+		t.Errorf("expected an invalid page size error, got %v", err)
+	}
+}
+
+type mockBansStore struct{}
+
+func (m *mockBansStore) FetchBans(target []byte, activeOnly bool, page, pageSize int) (defs.BanPage, error) {
+	return defs.BanPage{Page: page}, nil
+}
@@ -0,0 +1,103 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package admin
+
+import (
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	FlagsDefaultPageSize = 50
+	FlagsMaxPageSize     = 200
+)
+
+// Interface for database operations needed for listing flagged runs.
+type FlagsStore interface {
+	FetchRunFlags(status defs.RunFlagStatus, page, pageSize int) (defs.RunFlagPage, error)
+}
+
+// /admin/runs/flagged - list flagged runs, oldest first. An empty status
+// lists every flag.
+func Flags[T FlagsStore](store T, status defs.RunFlagStatus, page, pageSize int) (defs.RunFlagPage, error) {
+	switch status {
+	case "", defs.RunFlagPending, defs.RunFlagApproved, defs.RunFlagRejected:
+	default:
+		return defs.RunFlagPage{}, apierror.ErrInvalidParameter.Withf("invalid status %q", status)
+	}
+
+	if page < 1 {
+		return defs.RunFlagPage{}, apierror.ErrInvalidPage
+	}
+
+	if pageSize == 0 {
+		pageSize = FlagsDefaultPageSize
+	}
+
+	if pageSize < 1 || pageSize > FlagsMaxPageSize {
+		return defs.RunFlagPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", FlagsMaxPageSize)
+	}
+
+	return store.FetchRunFlags(status, page, pageSize)
+}
+
+// Interface for database operations needed for reviewing a flagged run.
+type ReviewFlagStore interface {
+	FetchRunFlagById(id int64) (defs.RunFlag, error)
+	ReviewRunFlag(id int64, status defs.RunFlagStatus, reviewer []byte) (bool, error)
+}
+
+// /admin/runs/approve and /admin/runs/reject - settle a pending flag. The
+// flag is returned as it was before the review.
+func ReviewFlag[T ReviewFlagStore](store T, id int64, approve bool, reviewer []byte) (defs.RunFlag, error) {
+	flag, err := store.FetchRunFlagById(id)
+	if err != nil {
+		return flag, err
+	}
+
+	status := defs.RunFlagRejected
+	if approve {
+		status = defs.RunFlagApproved
+	}
+
+	reviewed, err := store.ReviewRunFlag(id, status, reviewer)
+	if err != nil {
+		return flag, apierror.Internalf("failed to review run: %s", err)
+	}
+
+	if !reviewed {
+		return flag, apierror.ErrRunFlagReviewed
+	}
+
+	return flag, nil
+}
+
+// Interface for database operations needed for fetching a run's ledger.
+type LedgerStore interface {
+	FetchSessionLedger(uuid []byte, seed string) ([]defs.SessionLedgerEntry, error)
+}
+
+// /admin/runs/ledger - fetch the ledger of an account's run, oldest entry
+// first
+func Ledger[T LedgerStore](store T, uuid []byte, seed string) ([]defs.SessionLedgerEntry, error) {
+	if seed == "" {
+		return nil, apierror.ErrInvalidSeed.Withf("missing seed")
+	}
+
+	return store.FetchSessionLedger(uuid, seed)
+}
@@ -0,0 +1,51 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package admin
+
+import (
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	SystemLedgerDefaultPageSize = 50
+	SystemLedgerMaxPageSize     = 200
+)
+
+// Interface for database operations needed for browsing a system ledger.
+type SystemLedgerStore interface {
+	FetchSystemLedger(uuid []byte, flagged bool, page, pageSize int) (defs.SystemLedgerPage, error)
+}
+
+// /admin/account/systemLedger - list the egg and voucher changes of an
+// account's system save updates, newest first
+func SystemLedger[T SystemLedgerStore](store T, uuid []byte, flagged bool, page, pageSize int) (defs.SystemLedgerPage, error) {
+	if page < 1 {
+		return defs.SystemLedgerPage{}, apierror.ErrInvalidPage
+	}
+
+	if pageSize == 0 {
+		pageSize = SystemLedgerDefaultPageSize
+	}
+
+	if pageSize < 1 || pageSize > SystemLedgerMaxPageSize {
+		return defs.SystemLedgerPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", SystemLedgerMaxPageSize)
+	}
+
+	return store.FetchSystemLedger(uuid, flagged, page, pageSize)
+}
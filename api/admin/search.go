@@ -0,0 +1,79 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package admin
+
+import (
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	SearchDefaultPageSize = 25
+	SearchMaxPageSize     = 100
+
+	// substring searches can't use the username index, so require a few
+	// characters before scanning the whole table
+	SearchMinContainsLength = 3
+)
+
+// Interface for database operations needed for searching accounts.
+type SearchStore interface {
+	SearchAccounts(filter defs.AccountSearchFilter) (defs.AccountSearchPage, error)
+}
+
+// /admin/account/search - search accounts by partial username, linked ids and activity
+func Search[T SearchStore](store T, filter defs.AccountSearchFilter) (defs.AccountSearchPage, error) {
+	if filter.Page < 1 {
+		return defs.AccountSearchPage{}, apierror.ErrInvalidPage
+	}
+
+	if filter.PageSize == 0 {
+		filter.PageSize = SearchDefaultPageSize
+	}
+
+	if filter.PageSize < 1 || filter.PageSize > SearchMaxPageSize {
+		return defs.AccountSearchPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", SearchMaxPageSize)
+	}
+
+	switch filter.UsernameMatch {
+	case "":
+		filter.UsernameMatch = defs.UsernameMatchContains
+	case defs.UsernameMatchExact, defs.UsernameMatchPrefix, defs.UsernameMatchContains:
+	default:
+		return defs.AccountSearchPage{}, apierror.ErrInvalidParameter.Withf("invalid username match %q", filter.UsernameMatch)
+	}
+
+	if filter.Username != "" && filter.UsernameMatch == defs.UsernameMatchContains && len(filter.Username) < SearchMinContainsLength {
+		return defs.AccountSearchPage{}, apierror.ErrInvalidParameter.Withf("substring searches need at least %d characters", SearchMinContainsLength)
+	}
+
+	for _, date := range []string{filter.RegisteredFrom, filter.RegisteredTo, filter.ActiveFrom, filter.ActiveTo} {
+		if date == "" {
+			continue
+		}
+
+		_, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return defs.AccountSearchPage{}, apierror.ErrInvalidDate.Withf("invalid date %q: expected YYYY-MM-DD", date)
+		}
+	}
+
+	return store.SearchAccounts(filter)
+}
@@ -0,0 +1,175 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package apierror defines the errors returned to clients. Each error has a
+// stable code clients can match on instead of the message, which is free to
+// change.
+package apierror
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// An Error is an error meant for the client. Message is shown to the client
+// and Detail, which often holds a database error, is only logged.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Detail  error
+}
+
+// New returns an error with the given code, status and message.
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Detail != nil {
+		return e.Message + ": " + e.Detail.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Detail
+}
+
+// Is reports whether target is an Error with the same code, so errors.Is
+// matches copies made by Withf and Wrap.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Code == e.Code
+}
+
+// Withf returns a copy of the error with a formatted message.
+func (e *Error) Withf(format string, args ...any) *Error {
+	c := *e
+	c.Message = fmt.Sprintf(format, args...)
+
+	return &c
+}
+
+// Wrap returns a copy of the error with detail attached for logging.
+func (e *Error) Wrap(detail error) *Error {
+	c := *e
+	c.Detail = detail
+
+	return &c
+}
+
+// Internal wraps an error the client should not see.
+func Internal(err error) *Error {
+	return ErrInternal.Wrap(err)
+}
+
+// Internalf formats an error the client should not see.
+func Internalf(format string, args ...any) *Error {
+	return Internal(fmt.Errorf(format, args...))
+}
+
+// From returns err as an Error. Errors that aren't one already get the given
+// status and a generic code for it. Their text is only passed on for client
+// errors that don't come from the database.
+func From(err error, status int) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	if status >= http.StatusInternalServerError || isDatabaseError(err) {
+		return Internal(err)
+	}
+
+	code, ok := statusCodes[status]
+	if !ok {
+		code = ErrBadRequest.Code
+	}
+
+	return &Error{Code: code, Status: status, Message: err.Error()}
+}
+
+func isDatabaseError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+
+	return errors.As(err, &mysqlErr) ||
+		errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, sql.ErrTxDone) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn)
+}
+
+// A Problem is the json body of an error response, as described by RFC 9457,
+// extended with the error's code.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+// Problem returns the problem document of the error.
+func (e *Error) Problem() Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Message,
+		Code:   e.Code,
+	}
+}
+
+// Write writes the error as a problem document.
+func Write(w http.ResponseWriter, e *Error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Status)
+
+	_ = json.NewEncoder(w).Encode(e.Problem())
+}
+
+// Code returns the code of err, or an empty string if it isn't an Error.
+func Code(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	return ""
+}
+
+// statusCodes are the codes given to untyped errors of each status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusTooManyRequests:       "too_many_requests",
+}
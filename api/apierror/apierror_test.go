@@ -0,0 +1,83 @@
+package apierror
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	t.Run("Typed", func(t *testing.T) {
+		err := ErrInvalidSlot.Withf("slot id %d out of range", 7)
+		got := From(fmt.Errorf("wrapped: %w", err), http.StatusInternalServerError)
+		if got.Code != "invalid_slot" || got.Status != http.StatusBadRequest || got.Message != "slot id 7 out of range" {
+			t.Errorf("got %+v", got)
+		}
+	})
+	t.Run("Internal", func(t *testing.T) {
+		got := From(Internalf("failed to add account record: %s", "duplicate entry"), http.StatusBadRequest)
+		if got.Code != "internal_error" || got.Status != http.StatusInternalServerError {
+			t.Errorf("got %+v", got)
+		}
+		if got.Problem().Detail != "internal server error" {
+			t.Errorf("detail leaked: %q", got.Problem().Detail)
+		}
+	})
+	t.Run("UntypedServerError", func(t *testing.T) {
+		got := From(errors.New("connection refused"), http.StatusInternalServerError)
+		if got.Code != "internal_error" || got.Message != "internal server error" {
+			t.Errorf("got %+v", got)
+		}
+	})
+	t.Run("UntypedClientError", func(t *testing.T) {
+		got := From(errors.New("failed to convert page"), http.StatusBadRequest)
+		if got.Code != "bad_request" || got.Status != http.StatusBadRequest || got.Message != "failed to convert page" {
+			t.Errorf("got %+v", got)
+		}
+	})
+	t.Run("DatabaseClientError", func(t *testing.T) {
+		got := From(sql.ErrNoRows, http.StatusBadRequest)
+		if got.Code != "internal_error" || got.Message != "internal server error" {
+			t.Errorf("got %+v", got)
+		}
+	})
+}
+
+func TestIs(t *testing.T) {
+	err := ErrSessionOutOfDate.Withf("session out of date: not active")
+	if !errors.Is(err, ErrSessionOutOfDate) {
+		t.Errorf("expected copy to match its code")
+	}
+	if errors.Is(err, ErrInvalidSlot) {
+		t.Errorf("expected other codes not to match")
+	}
+	if !errors.Is(Internal(sql.ErrNoRows), sql.ErrNoRows) {
+		t.Errorf("expected detail to be unwrapped")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, ErrSessionOutOfDate.Withf("session out of date: existing wave index is greater"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "session out of date: existing wave index is greater", Code: "session_out_of_date"}
+	if problem != want {
+		t.Errorf("expected %+v, got %+v", want, problem)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package apierror
+
+import "net/http"
+
+// Codes are part of the api: once released, a code keeps its meaning.
+// Messages may be reworded at any time.
+
+// generic
+var (
+	ErrBadRequest   = New("bad_request", http.StatusBadRequest, "bad request")
+	ErrUnauthorized = New("unauthorized", http.StatusUnauthorized, "unauthorized")
+	ErrForbidden    = New("forbidden", http.StatusForbidden, "forbidden")
+	ErrNotFound     = New("not_found", http.StatusNotFound, "not found")
+	ErrConflict     = New("conflict", http.StatusConflict, "conflict")
+	ErrInternal     = New("internal_error", http.StatusInternalServerError, "internal server error")
+
+	ErrInvalidParameter = New("invalid_parameter", http.StatusBadRequest, "invalid parameter")
+	ErrInvalidPage      = New("invalid_page", http.StatusBadRequest, "invalid page")
+	ErrInvalidPageSize  = New("invalid_page_size", http.StatusBadRequest, "invalid page size")
+	ErrInvalidDate      = New("invalid_date", http.StatusBadRequest, "invalid date")
+)
+
+// request bodies
+var (
+	ErrBodyTooLarge       = New("body_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+	ErrBodyTooDeep        = New("body_too_deep", http.StatusRequestEntityTooLarge, "request body is nested too deep")
+	ErrBodyArrayTooLong   = New("body_array_too_long", http.StatusRequestEntityTooLarge, "request body has an array that is too long")
+	ErrBodyMalformed      = New("body_malformed", http.StatusBadRequest, "request body is not valid json")
+	ErrBodyUnknownField   = New("body_unknown_field", http.StatusBadRequest, "request body has an unknown field")
+	ErrBodyTrailingValues = New("body_trailing_values", http.StatusBadRequest, "request body has data after the json value")
+)
+
+// accounts
+var (
+	ErrUserNotFound       = New("user_not_found", http.StatusNotFound, "username does not exist on the server")
+	ErrInvalidUsername    = New("invalid_username", http.StatusBadRequest, "invalid username")
+	ErrUsernameNotAllowed = New("username_not_allowed", http.StatusBadRequest, "username is not allowed")
+	ErrUsernameTaken      = New("username_taken", http.StatusConflict, "username is already taken")
+	ErrUsernameUnchanged  = New("username_unchanged", http.StatusBadRequest, "username is unchanged")
+	ErrUsernameCooldown   = New("username_cooldown", http.StatusBadRequest, "username can not be changed yet")
+	ErrInvalidPassword    = New("invalid_password", http.StatusBadRequest, "invalid password")
+	ErrInvalidCredentials = New("invalid_credentials", http.StatusUnauthorized, "invalid credentials")
+	ErrInvalidToken       = New("invalid_token", http.StatusUnauthorized, "invalid token")
+	ErrProfilePrivate     = New("profile_private", http.StatusForbidden, "profile is private")
+	ErrRoleNotFound       = New("role_not_found", http.StatusNotFound, "role does not exist")
+	ErrDeletionNotPending = New("deletion_not_scheduled", http.StatusConflict, "account is not scheduled for deletion")
+	ErrInvalidAppeal      = New("invalid_appeal", http.StatusBadRequest, "invalid appeal")
+	ErrNoBanToAppeal      = New("no_ban_to_appeal", http.StatusConflict, "no active ban to appeal, or ban has already been appealed")
+)
+
+// saves
+var (
+	ErrSaveNotFound           = New("save_not_found", http.StatusNotFound, "save does not exist")
+	ErrInvalidSlot            = New("invalid_slot", http.StatusBadRequest, "slot id out of range")
+	ErrInvalidDataType        = New("invalid_data_type", http.StatusBadRequest, "invalid data type")
+	ErrInvalidSaveData        = New("invalid_save_data", http.StatusBadRequest, "invalid save data")
+	ErrInvalidGameVersion     = New("invalid_game_version", http.StatusBadRequest, "invalid game version")
+	ErrMissingClientSessionId = New("missing_client_session_id", http.StatusBadRequest, "missing clientSessionId")
+
+	// ErrSessionOutOfDate is returned when a save is rejected because the
+	// client's session is behind the stored one. The message says why.
+	ErrSessionOutOfDate = New("session_out_of_date", http.StatusBadRequest, "session out of date")
+)
+
+// daily runs and events
+var (
+	ErrInvalidEvent       = New("invalid_event", http.StatusBadRequest, "invalid event")
+	ErrEventNotFound      = New("event_not_found", http.StatusNotFound, "event does not exist")
+	ErrInvalidSeed        = New("invalid_seed", http.StatusBadRequest, "invalid seed")
+	ErrDailyRunNotFound   = New("daily_run_not_found", http.StatusNotFound, "daily run does not exist")
+	ErrDailyRunNotPending = New("daily_run_not_upcoming", http.StatusConflict, "only upcoming daily runs can be changed")
+	ErrOverrideNotFound   = New("override_not_found", http.StatusNotFound, "no override is scheduled")
+	ErrSecretNotFound     = New("secret_not_found", http.StatusNotFound, "no daily seed secret is active")
+	ErrSecretsFromConfig  = New("secrets_from_config", http.StatusConflict, "daily seed secrets are set through config")
+)
+
+// admin
+var (
+	ErrRunFlagReviewed = New("run_flag_reviewed", http.StatusConflict, "run has already been reviewed")
+)
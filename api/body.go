@@ -0,0 +1,256 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+)
+
+// BodyLimit caps the json body of a request. The body is read up to MaxBytes
+// and its structure checked before it is decoded, so oversized or deeply
+// nested payloads are rejected without being decoded.
+type BodyLimit struct {
+	MaxBytes       int64
+	MaxDepth       int
+	MaxArrayLength int
+
+	// DisallowUnknownFields rejects fields the target type doesn't have.
+	// Saves are left lenient, since the game adds fields before the server
+	// knows about them.
+	DisallowUnknownFields bool
+}
+
+var (
+	// DefaultBodyLimit applies to json bodies of paths missing from
+	// BodyLimits.
+	DefaultBodyLimit = BodyLimit{MaxBytes: 1 << 20, MaxDepth: 32, MaxArrayLength: 10000}
+
+	// BodyLimits holds the body limits of each path that takes a json body.
+	// Paths with wildcards are keyed by their route pattern.
+	BodyLimits = map[string]BodyLimit{
+		"/savedata/updateall":      {MaxBytes: 8 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/savedata/session/update": {MaxBytes: 2 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/savedata/session/clear":  {MaxBytes: 2 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/savedata/system/update":  {MaxBytes: 8 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+
+		"/v2/saves":                       {MaxBytes: 8 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/v2/saves/system":                {MaxBytes: 8 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/v2/saves/sessions/{slot}":       {MaxBytes: 2 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+		"/v2/saves/sessions/{slot}/clear": {MaxBytes: 2 << 20, MaxDepth: 32, MaxArrayLength: 10000},
+	}
+)
+
+// bodyRejections counts rejected request bodies by path and error code. It is
+// served on /admin/metrics.
+var bodyRejections = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+func countBodyRejection(key string) {
+	bodyRejections.mu.Lock()
+	bodyRejections.counts[key]++
+	bodyRejections.mu.Unlock()
+}
+
+// bodyRejectionCounts returns a copy of the rejected request body counts.
+func bodyRejectionCounts() map[string]int64 {
+	bodyRejections.mu.Lock()
+	defer bodyRejections.mu.Unlock()
+
+	return maps.Clone(bodyRejections.counts)
+}
+
+// ParseBodyLimits parses the max body sizes of paths, as read from the
+// bodylimits env var, in the form "/savedata/updateall:8388608,...". Paths
+// not in the list keep their default limits.
+func ParseBodyLimits(value string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		path, size, ok := strings.Cut(entry, ":")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid body limit %q", entry)
+		}
+
+		maxBytes, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || maxBytes < 1 {
+			return nil, fmt.Errorf("invalid body size in %q", entry)
+		}
+
+		sizes[path] = maxBytes
+	}
+
+	return sizes, nil
+}
+
+// SetBodySizes overrides the max body size of the given paths.
+func SetBodySizes(sizes map[string]int64) {
+	for path, maxBytes := range sizes {
+		limit, ok := BodyLimits[path]
+		if !ok {
+			limit = DefaultBodyLimit
+		}
+
+		limit.MaxBytes = maxBytes
+		BodyLimits[path] = limit
+	}
+}
+
+// decodeBody decodes the request's json body into v, enforcing the body
+// limit of the request's path. Limit violations are counted and returned as
+// an apierror with one of the body codes.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	return decodeBodyFor(w, r, r.URL.Path, v)
+}
+
+// decodeBodyFor is decodeBody for routes with wildcards, whose limit is looked
+// up by route pattern rather than path.
+func decodeBodyFor(w http.ResponseWriter, r *http.Request, route string, v any) error {
+	limit, ok := BodyLimits[route]
+	if !ok {
+		limit = DefaultBodyLimit
+	}
+
+	err := readBody(w, r, limit, v)
+	if err != nil {
+		countBodyRejection(route + " " + apierror.Code(err))
+	}
+
+	return err
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, limit BodyLimit, v any) error {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit.MaxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return apierror.ErrBodyTooLarge.Withf("request body exceeds %d bytes", limit.MaxBytes)
+		}
+
+		return apierror.ErrBodyMalformed.Withf("failed to read request body: %s", err)
+	}
+
+	err = checkStructure(data, limit.MaxDepth, limit.MaxArrayLength)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if limit.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	err = decoder.Decode(v)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return apierror.ErrBodyUnknownField.Withf("failed to decode request body: %s", err)
+		}
+
+		return apierror.ErrBodyMalformed.Withf("failed to decode request body: %s", err)
+	}
+
+	if decoder.More() {
+		return apierror.ErrBodyTrailingValues
+	}
+
+	return nil
+}
+
+// checkStructure scans json for nesting deeper than maxDepth and arrays
+// longer than maxArrayLength, without decoding it. A limit of 0 disables the
+// check.
+func checkStructure(data []byte, maxDepth, maxArrayLength int) error {
+	// element counts of the open containers, -1 for objects
+	var open []int
+	var inString, escaped bool
+
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '}', ']':
+			if len(open) == 0 {
+				return apierror.ErrBodyMalformed.Withf("unbalanced brackets in request body")
+			}
+
+			open = open[:len(open)-1]
+
+			continue
+		case ',':
+			if len(open) > 0 && open[len(open)-1] > 0 {
+				open[len(open)-1]++
+				if maxArrayLength > 0 && open[len(open)-1] > maxArrayLength {
+					return apierror.ErrBodyArrayTooLong.Withf("request body has an array longer than %d items", maxArrayLength)
+				}
+			}
+
+			continue
+		}
+
+		// c is part of a value, so an empty array now has its first item
+		if len(open) > 0 && open[len(open)-1] == 0 {
+			open[len(open)-1] = 1
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			if maxDepth > 0 && len(open) >= maxDepth {
+				return apierror.ErrBodyTooDeep.Withf("request body is nested deeper than %d levels", maxDepth)
+			}
+
+			if c == '{' {
+				open = append(open, -1)
+			} else {
+				open = append(open, 0)
+			}
+		}
+	}
+
+	return nil
+}
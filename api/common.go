@@ -18,15 +18,22 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/pagefaultgames/rogueserver/api/account"
+	"github.com/pagefaultgames/rogueserver/api/admin"
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/api/daily"
 	"github.com/pagefaultgames/rogueserver/db"
+	"github.com/pagefaultgames/rogueserver/defs"
 )
 
 func Init(mux *http.ServeMux) error {
@@ -40,16 +47,62 @@ func Init(mux *http.ServeMux) error {
 		return err
 	}
 
+	err = scheduleDiscordRoleSync(db.Store)
+	if err != nil {
+		return err
+	}
+
+	err = scheduleBanExpiry(db.Store)
+	if err != nil {
+		return err
+	}
+
+	err = scheduleAccountPurge(db.Store)
+	if err != nil {
+		return err
+	}
+
+	err = scheduleLedgerPrune(db.Store)
+	if err != nil {
+		return err
+	}
+
+	err = scheduleHistoryPrune(db.Store)
+	if err != nil {
+		return err
+	}
+
+	err = scheduleDexStats(db.Store)
+	if err != nil {
+		return err
+	}
+
 	// account
 	mux.HandleFunc("GET /account/info", handleAccountInfo)
 	mux.HandleFunc("POST /account/register", handleAccountRegister)
 	mux.HandleFunc("POST /account/login", handleAccountLogin)
 	mux.HandleFunc("POST /account/changepw", handleAccountChangePW)
+	mux.HandleFunc("POST /account/username", handleAccountChangeUsername)
 	mux.HandleFunc("GET /account/logout", handleAccountLogout)
+	mux.HandleFunc("POST /account/banappeal", handleAccountBanAppeal)
+	mux.HandleFunc("GET /account/export", handleAccountExport)
+	mux.HandleFunc("POST /account/delete", handleAccountDelete)
+	mux.HandleFunc("POST /account/delete/cancel", handleAccountDeleteCancel)
+	mux.HandleFunc("GET /account/stats", handleAccountStats)
+	mux.HandleFunc("POST /account/profile", handleAccountProfile)
+
+	// profile
+	mux.HandleFunc("GET /profile/{username}", handleProfile)
 
 	// game
 	mux.HandleFunc("GET /game/titlestats", handleGameTitleStats)
 	mux.HandleFunc("GET /game/classicsessioncount", handleGameClassicSessionCount)
+	mux.HandleFunc("GET /game/stats", handleGameStats)
+	mux.HandleFunc("GET /game/dexstats", handleGameDexStats)
+
+	// stats
+	mux.HandleFunc("GET /stats/rankings", handleStatRankings)
+	mux.HandleFunc("GET /stats/rankingpagecount", handleStatRankingPageCount)
 
 	// savedata
 	mux.HandleFunc("/savedata/session/{action}", handleSession)
@@ -57,38 +110,113 @@ func Init(mux *http.ServeMux) error {
 
 	// new session
 	mux.HandleFunc("POST /savedata/updateall", handleUpdateAll)
+	mux.HandleFunc("GET /savedata/history", handleSessionHistory)
+	mux.HandleFunc("GET /savedata/history/{id}", handleSessionHistoryRun)
 
 	// daily
 	mux.HandleFunc("GET /daily/seed", handleDailySeed)
 	mux.HandleFunc("GET /daily/rankings", handleDailyRankings)
+	mux.HandleFunc("GET /daily/rankings/me", handleDailyPlayerRanking)
 	mux.HandleFunc("GET /daily/rankingpagecount", handleDailyRankingPageCount)
+	mux.HandleFunc("GET /daily/history", handleDailyHistory)
+	mux.HandleFunc("GET /daily/schedule", handleDailySchedule)
+	mux.HandleFunc("GET /daily/event/rankings", handleDailyEventRankings)
+	mux.HandleFunc("GET /daily/event/rankingpagecount", handleDailyEventRankingPageCount)
 
 	// auth
 	mux.HandleFunc("/auth/{provider}/callback", handleProviderCallback)
 	mux.HandleFunc("/auth/{provider}/logout", handleProviderLogout)
 
 	// admin
-	mux.HandleFunc("POST /admin/account/discordLink", handleAdminDiscordLink)
-	mux.HandleFunc("POST /admin/account/discordUnlink", handleAdminDiscordUnlink)
-	mux.HandleFunc("POST /admin/account/googleLink", handleAdminGoogleLink)
-	mux.HandleFunc("POST /admin/account/googleUnlink", handleAdminGoogleUnlink)
-	mux.HandleFunc("GET /admin/account/adminSearch", handleAdminSearch)
+	mux.HandleFunc("POST /admin/account/discordLink", requirePermission(defs.PermissionAccountLink, handleAdminDiscordLink))
+	mux.HandleFunc("POST /admin/account/discordUnlink", requirePermission(defs.PermissionAccountLink, handleAdminDiscordUnlink))
+	mux.HandleFunc("POST /admin/account/googleLink", requirePermission(defs.PermissionAccountLink, handleAdminGoogleLink))
+	mux.HandleFunc("POST /admin/account/googleUnlink", requirePermission(defs.PermissionAccountLink, handleAdminGoogleUnlink))
+	mux.HandleFunc("GET /admin/account/adminSearch", requirePermission(defs.PermissionAccountSearch, handleAdminSearch))
+	mux.HandleFunc("GET /admin/account/search", requirePermission(defs.PermissionAccountSearch, handleAdminAccountSearch))
+	mux.HandleFunc("GET /admin/roles", requirePermission(defs.PermissionRoleManage, handleAdminRoles))
+	mux.HandleFunc("POST /admin/account/roleGrant", requirePermission(defs.PermissionRoleManage, handleAdminRoleGrant))
+	mux.HandleFunc("POST /admin/account/roleRevoke", requirePermission(defs.PermissionRoleManage, handleAdminRoleRevoke))
+	mux.HandleFunc("GET /admin/audit", requirePermission(defs.PermissionAuditView, handleAdminAuditLog))
+	mux.HandleFunc("GET /admin/metrics", requirePermission(defs.PermissionAuditView, handleAdminMetrics))
+	mux.HandleFunc("POST /admin/account/ban", requirePermission(defs.PermissionAccountBan, handleAdminBan))
+	mux.HandleFunc("POST /admin/account/unban", requirePermission(defs.PermissionAccountBan, handleAdminUnban))
+	mux.HandleFunc("GET /admin/bans", requirePermission(defs.PermissionAccountBan, handleAdminBans))
+	mux.HandleFunc("GET /admin/account/systemLedger", requirePermission(defs.PermissionAccountBan, handleAdminSystemLedger))
+	mux.HandleFunc("POST /admin/account/delete", requirePermission(defs.PermissionAccountDelete, handleAdminAccountDelete))
+	mux.HandleFunc("GET /admin/account/export", requirePermission(defs.PermissionAccountDelete, handleAdminAccountExport))
+	mux.HandleFunc("POST /admin/daily/override", requirePermission(defs.PermissionDailyManage, handleAdminDailyOverride))
+	mux.HandleFunc("POST /admin/daily/restore", requirePermission(defs.PermissionDailyManage, handleAdminDailyRestore))
+	mux.HandleFunc("POST /admin/daily/event", requirePermission(defs.PermissionDailyManage, handleAdminDailyEvent))
+	mux.HandleFunc("POST /admin/daily/eventRemove", requirePermission(defs.PermissionDailyManage, handleAdminDailyEventRemove))
+	mux.HandleFunc("POST /admin/daily/rotateSecret", requirePermission(defs.PermissionDailyManage, handleAdminDailyRotateSecret))
+	mux.HandleFunc("GET /admin/runs/flagged", requirePermission(defs.PermissionLeaderboardModerate, handleAdminFlaggedRuns))
+	mux.HandleFunc("GET /admin/runs/flagged/{id}", requirePermission(defs.PermissionLeaderboardModerate, handleAdminFlaggedRun))
+	mux.HandleFunc("POST /admin/runs/approve", requirePermission(defs.PermissionLeaderboardModerate, handleAdminRunApprove))
+	mux.HandleFunc("POST /admin/runs/reject", requirePermission(defs.PermissionLeaderboardModerate, handleAdminRunReject))
+	mux.HandleFunc("GET /admin/runs/ledger", requirePermission(defs.PermissionLeaderboardModerate, handleAdminRunLedger))
+
+	// v2, described by the spec it serves
+	mux.HandleFunc("GET /v2/openapi.json", handleV2OpenAPI)
+
+	// v2 account
+	mux.HandleFunc("POST /v2/account", handleV2AccountRegister)
+	mux.HandleFunc("GET /v2/account", handleAccountInfo)
+	mux.HandleFunc("POST /v2/account/sessions", handleV2AccountLogin)
+	mux.HandleFunc("DELETE /v2/account/sessions/current", handleV2AccountLogout)
+	mux.HandleFunc("PUT /v2/account/password", handleV2AccountPassword)
+	mux.HandleFunc("PUT /v2/account/username", handleV2AccountUsername)
+	mux.HandleFunc("PUT /v2/account/profile", handleV2AccountProfile)
+	mux.HandleFunc("GET /v2/account/stats", handleAccountStats)
+	mux.HandleFunc("GET /v2/account/export", handleAccountExport)
+	mux.HandleFunc("POST /v2/account/ban-appeal", handleV2AccountBanAppeal)
+	mux.HandleFunc("POST /v2/account/deletion", handleV2AccountDelete)
+	mux.HandleFunc("DELETE /v2/account/deletion", handleV2AccountDeleteCancel)
+	mux.HandleFunc("GET /v2/profiles/{username}", handleProfile)
+
+	// v2 game and stats
+	mux.HandleFunc("GET /v2/game/titlestats", handleGameTitleStats)
+	mux.HandleFunc("GET /v2/game/stats", handleGameStats)
+	mux.HandleFunc("GET /v2/game/dexstats", handleGameDexStats)
+	mux.HandleFunc("GET /v2/stats/rankings", handleV2StatRankings)
+
+	// v2 saves
+	mux.HandleFunc("PUT /v2/saves", handleV2SavesPut)
+	mux.HandleFunc("GET /v2/saves/system", handleV2SystemGet)
+	mux.HandleFunc("PUT /v2/saves/system", handleV2SystemPut)
+	mux.HandleFunc("DELETE /v2/saves/system", handleV2SystemDelete)
+	mux.HandleFunc("POST /v2/saves/system/verify", handleV2SystemVerify)
+	mux.HandleFunc("GET /v2/saves/sessions/{slot}", handleV2SessionGet)
+	mux.HandleFunc("PUT /v2/saves/sessions/{slot}", handleV2SessionPut)
+	mux.HandleFunc("DELETE /v2/saves/sessions/{slot}", handleV2SessionDelete)
+	mux.HandleFunc("POST /v2/saves/sessions/{slot}/clear", handleV2SessionClear)
+	mux.HandleFunc("GET /v2/saves/sessions/{slot}/newclear", handleV2SessionNewClear)
+	mux.HandleFunc("GET /v2/saves/history", handleSessionHistory)
+	mux.HandleFunc("GET /v2/saves/history/{id}", handleSessionHistoryRun)
+
+	// v2 daily
+	mux.HandleFunc("GET /v2/daily/seed", handleV2DailySeed)
+	mux.HandleFunc("GET /v2/daily/rankings", handleV2DailyRankings)
+	mux.HandleFunc("GET /v2/daily/rankings/me", handleDailyPlayerRanking)
+	mux.HandleFunc("GET /v2/daily/history", handleDailyHistory)
+	mux.HandleFunc("GET /v2/daily/schedule", handleDailySchedule)
+	mux.HandleFunc("GET /v2/daily/events/{id}/rankings", handleV2DailyEventRankings)
 
 	return nil
 }
 
 func tokenFromRequest(r *http.Request) ([]byte, error) {
 	if r.Header.Get("Authorization") == "" {
-		return nil, fmt.Errorf("missing token")
+		return nil, apierror.ErrInvalidToken.Withf("missing token")
 	}
 
 	token, err := base64.StdEncoding.DecodeString(r.Header.Get("Authorization"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode token: %s", err)
+		return nil, apierror.ErrInvalidToken.Withf("failed to decode token: %s", err)
 	}
 
 	if len(token) != account.TokenSize {
-		return nil, fmt.Errorf("invalid token length: got %d, expected %d", len(token), account.TokenSize)
+		return nil, apierror.ErrInvalidToken.Withf("invalid token length: got %d, expected %d", len(token), account.TokenSize)
 	}
 
 	return token, nil
@@ -111,15 +239,114 @@ func tokenAndUuidFromRequest(r *http.Request) ([]byte, []byte, error) {
 
 	uuid, err := db.Store.FetchUUIDFromToken(token)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to validate token: %s", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, apierror.ErrInvalidToken.Withf("token not found")
+		}
+
+		return nil, nil, apierror.Internalf("failed to validate token: %s", err)
 	}
 
 	return token, uuid, nil
 }
 
+// adminHandlerFunc is a handler that has already been authenticated and
+// authorized. uuid is the account making the request.
+type adminHandlerFunc func(w http.ResponseWriter, r *http.Request, uuid []byte)
+
+// requirePermission authenticates the request and rejects it with 403 unless
+// one of the account's roles grants permission.
+func requirePermission(permission defs.Permission, handler adminHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uuid, err := uuidFromRequest(r)
+		if err != nil {
+			httpError(w, r, err, http.StatusUnauthorized)
+			return
+		}
+
+		allowed, err := db.Store.HasAccountPermission(uuid, permission)
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to check permissions: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		if !allowed {
+			httpError(w, r, apierror.ErrForbidden.Withf("user does not have the required permission"), http.StatusForbidden)
+			return
+		}
+
+		handler(w, r, uuid)
+	}
+}
+
+// auditAdminAction records an admin action in the audit log. The action has
+// already happened by the time this is called, so a failed write is logged
+// rather than surfaced to the client.
+func auditAdminAction(r *http.Request, actor []byte, action defs.AuditAction, target []byte, before, after string) {
+	log.Printf("%s: %x performed %s on %x (%q -> %q)", r.URL.Path, actor, action, target, before, after)
+
+	err := admin.Record(db.Store, actor, action, target, before, after, clientIP(r))
+	if err != nil {
+		log.Printf("%s: %s", r.URL.Path, err)
+	}
+}
+
+// TrustedProxies is the number of reverse proxies in front of the server,
+// each of which appends the address it got the request from to
+// X-Forwarded-For. With none, X-Forwarded-For is ignored, as the client can
+// set it to anything.
+var TrustedProxies = 0
+
+// clientIP returns the address of the client. Behind TrustedProxies proxies
+// that is the hop the outermost one added to X-Forwarded-For; the hops before
+// it come from the client and can't be trusted.
+func clientIP(r *http.Request) string {
+	if TrustedProxies > 0 {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(header, ",")...)
+		}
+
+		if len(hops) >= TrustedProxies {
+			if ip := strings.TrimSpace(hops[len(hops)-TrustedProxies]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// httpError answers with err as a problem document. Errors that aren't an
+// apierror get the given status; their text is only shown for client errors,
+// and logged in full either way.
 func httpError(w http.ResponseWriter, r *http.Request, err error, code int) {
 	log.Printf("%s: %s\n", r.URL.Path, err)
-	http.Error(w, err.Error(), code)
+	apierror.Write(w, apierror.From(err, code))
+}
+
+// notModified sets the caching headers for a response at the given version,
+// and answers with 304 if the client already has it. Unversioned responses
+// are left alone.
+func notModified(w http.ResponseWriter, r *http.Request, version string) bool {
+	if version == "" {
+		return false
+	}
+
+	etag := `"` + version + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=10")
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
 }
 
 func writeJSON(w http.ResponseWriter, r *http.Request, data any) {
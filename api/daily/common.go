@@ -18,65 +18,65 @@
 package daily
 
 import (
-	"crypto/md5"
-	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/pagefaultgames/rogueserver/db"
 	"github.com/robfig/cron/v3"
 )
 
-const secondsPerDay = 60 * 60 * 24
-
 var (
 	scheduler = cron.New(cron.WithLocation(time.UTC))
-	secret    []byte
+
+	// Location is the timezone the daily run date is counted in.
+	Location = time.UTC
+	// Rollover is how long after midnight in Location a new daily run starts.
+	Rollover time.Duration
 )
 
 func Init() error {
-	var err error
-
-	secret, err = os.ReadFile("secret.key")
+	err := loadSecrets(db.Store)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to read daily seed secret: %s", err)
-		}
-
-		newSecret := make([]byte, 32)
-		_, err := rand.Read(newSecret)
-		if err != nil {
-			return fmt.Errorf("failed to generate daily seed secret: %s", err)
-		}
-
-		err = os.WriteFile("secret.key", newSecret, 0400)
-		if err != nil {
-			return fmt.Errorf("failed to write daily seed secret: %s", err)
-		}
-
-		secret = newSecret
+		return err
 	}
 
-	seed, err := db.Store.TryAddDailyRun(Seed())
+	seed, err := addDailyRun(db.Store, Today())
 	if err != nil {
 		log.Print(err)
 	}
 
 	log.Printf("Daily Run Seed: %s", seed)
 
-	_, err = scheduler.AddFunc("@daily", func() {
+	err = RebuildLeaderboards(db.Store)
+	if err != nil {
+		log.Print(err)
+	}
+
+	spec := fmt.Sprintf("CRON_TZ=%s %d %d * * *", Location, int(Rollover.Minutes())%60, int(Rollover.Hours()))
+	_, err = scheduler.AddFunc(spec, func() {
 		time.Sleep(time.Second)
 
-		seed, err = db.Store.TryAddDailyRun(Seed())
+		// pick up secrets rotated by other instances
+		err := loadSecrets(db.Store)
+		if err != nil {
+			log.Printf("failed to reload daily seed secrets: %s", err)
+		}
+
+		seed, err = addDailyRun(db.Store, Today())
 		if err != nil {
 			log.Printf("error while recording new daily: %s", err)
 		} else {
 			log.Printf("Daily Run Seed: %s", seed)
 		}
+
+		err = RebuildLeaderboards(db.Store)
+		if err != nil {
+			log.Print(err)
+		}
 	})
 	if err != nil {
 		return err
@@ -87,15 +87,73 @@ func Init() error {
 	return nil
 }
 
-func Seed() string {
-	return base64.StdEncoding.EncodeToString(deriveSeed(time.Now().UTC()))
+// ParseRollover parses a rollover time of day in 15:04 format, as read from
+// the dailyrollover env var.
+func ParseRollover(value string) (time.Duration, error) {
+	rollover, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rollover time %q", value)
+	}
+
+	return time.Duration(rollover.Hour())*time.Hour + time.Duration(rollover.Minute())*time.Minute, nil
+}
+
+// Date returns the daily run date that t falls on, as midnight UTC of that
+// calendar day.
+func Date(t time.Time) time.Time {
+	local := t.In(Location)
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	if sinceMidnight < Rollover {
+		date = date.AddDate(0, 0, -1)
+	}
+
+	return date
+}
+
+// Today returns the current daily run date in 2006-01-02 format.
+func Today() string {
+	return Date(time.Now()).Format(time.DateOnly)
+}
+
+type addDailyRunStore interface {
+	FetchDailyRunOverride(date string) (string, error)
+	TryAddDailyRun(date, seed string) (string, error)
 }
 
-func deriveSeed(seedTime time.Time) []byte {
-	day := make([]byte, 8)
-	binary.BigEndian.PutUint64(day, uint64(seedTime.Unix()/secondsPerDay))
+// addDailyRun records the run for date, using the seed an admin scheduled for
+// it if there is one. If the run already exists its seed is kept, and checked
+// against the one this instance derives.
+func addDailyRun[T addDailyRunStore](store T, date string) (string, error) {
+	seed, err := store.FetchDailyRunOverride(date)
+	override := err == nil
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return "", err
+		}
+
+		derived, err := deriveSeed(day)
+		if err != nil {
+			return "", err
+		}
+
+		seed = base64.StdEncoding.EncodeToString(derived)
+	}
+
+	actualSeed, err := store.TryAddDailyRun(date, seed)
+	if err != nil {
+		return "", err
+	}
 
-	hashedSeed := md5.Sum(append(day, secret...))
+	if !override && actualSeed != seed {
+		log.Printf("WARNING: daily seed for %s is %s, but this instance derives %s; its daily seed secrets do not match the other instances", date, actualSeed, seed)
+	}
 
-	return hashedSeed[:]
+	return actualSeed, nil
 }
@@ -0,0 +1,111 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package daily
+
+import (
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Interface for database operations needed for adding an event run.
+type AddEventStore interface {
+	AddEventRun(name, seed string, starts, ends time.Time, createdBy []byte) (int64, error)
+}
+
+// /admin/daily/event - schedule an event run. An empty seed picks a random one.
+func AddEvent[T AddEventStore](store T, name, seed string, starts, ends time.Time, createdBy []byte) (defs.EventRun, error) {
+	if name == "" || len(name) > eventNameMaxLength {
+		return defs.EventRun{}, apierror.ErrInvalidEvent.Withf("name must be between 1 and %d characters", eventNameMaxLength)
+	}
+
+	if !ends.After(starts) {
+		return defs.EventRun{}, apierror.ErrInvalidEvent.Withf("event must end after it starts")
+	}
+
+	if !ends.After(time.Now()) {
+		return defs.EventRun{}, apierror.ErrInvalidEvent.Withf("event must end in the future")
+	}
+
+	seed, err := validateSeed(seed)
+	if err != nil {
+		return defs.EventRun{}, err
+	}
+
+	starts, ends = starts.UTC(), ends.UTC()
+
+	id, err := store.AddEventRun(name, seed, starts, ends, createdBy)
+	if err != nil {
+		return defs.EventRun{}, apierror.Internalf("failed to add event: %s", err)
+	}
+
+	return defs.EventRun{
+		Id:     id,
+		Name:   name,
+		Seed:   seed,
+		Starts: starts.Format(time.DateTime),
+		Ends:   ends.Format(time.DateTime),
+	}, nil
+}
+
+// Interface for database operations needed for removing an event run.
+type RemoveEventStore interface {
+	FetchEventRun(id int64) (defs.EventRun, error)
+	RemoveEventRun(id int64) (bool, error)
+}
+
+// /admin/daily/eventRemove - remove an event run along with its leaderboard
+func RemoveEvent[T RemoveEventStore](store T, id int64) (defs.EventRun, error) {
+	event, err := store.FetchEventRun(id)
+	if err != nil {
+		return event, apierror.ErrEventNotFound
+	}
+
+	_, err = store.RemoveEventRun(id)
+	if err != nil {
+		return event, apierror.Internalf("failed to remove event: %s", err)
+	}
+
+	return event, nil
+}
+
+// Interface for database operations needed for fetching event rankings.
+type EventRankingsStore interface {
+	FetchEventRun(id int64) (defs.EventRun, error)
+	FetchEventRankings(event int64, page int) ([]defs.DailyRanking, error)
+}
+
+// /daily/event/rankings - fetch the rankings of an event run
+func EventRankings[T EventRankingsStore](store T, id int64, page int) ([]defs.DailyRanking, error) {
+	_, err := store.FetchEventRun(id)
+	if err != nil {
+		return nil, apierror.ErrEventNotFound
+	}
+
+	return store.FetchEventRankings(id, page)
+}
+
+type EventRankingPageCountStore interface {
+	FetchEventRankingPageCount(event int64) (int, error)
+}
+
+// /daily/event/rankingpagecount - fetch the ranking page count of an event run
+func EventRankingPageCount[T EventRankingPageCountStore](store T, id int64) (int, error) {
+	return store.FetchEventRankingPageCount(id)
+}
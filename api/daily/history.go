@@ -0,0 +1,39 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package daily
+
+import (
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const HistoryPageSize = 30
+
+// Interface for database operations needed for fetching the daily history.
+type HistoryStore interface {
+	FetchDailyHistory(to string, page, pageSize int) (defs.DailyHistoryPage, error)
+}
+
+// /daily/history - list past daily runs with their participants and winner
+func History[T HistoryStore](store T, page int) (defs.DailyHistoryPage, error) {
+	if page < 1 {
+		return defs.DailyHistoryPage{}, apierror.ErrInvalidPage
+	}
+
+	return store.FetchDailyHistory(Today(), page, HistoryPageSize)
+}
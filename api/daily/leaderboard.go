@@ -0,0 +1,290 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package daily
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// leaderboard holds the ranked entries of a single category for the current
+// period, ordered the same way as the ranking queries.
+type leaderboard struct {
+	entries []*defs.LeaderboardEntry
+	ranks   []int
+	index   map[string]int
+}
+
+func newLeaderboard(entries []*defs.LeaderboardEntry) *leaderboard {
+	board := &leaderboard{entries: entries}
+	board.sort()
+
+	return board
+}
+
+func compareEntries(a, b *defs.LeaderboardEntry) int {
+	if a.Score != b.Score {
+		return b.Score - a.Score
+	}
+
+	if c := strings.Compare(a.Timestamp, b.Timestamp); c != 0 {
+		return c
+	}
+
+	return strings.Compare(string(a.Uuid), string(b.Uuid))
+}
+
+// sort orders the entries and recomputes ranks and the index. Entries tied on
+// both score and timestamp share a rank, like RANK() in the ranking queries.
+func (b *leaderboard) sort() {
+	slices.SortFunc(b.entries, compareEntries)
+
+	b.ranks = make([]int, len(b.entries))
+	b.index = make(map[string]int, len(b.entries))
+	for i, entry := range b.entries {
+		b.ranks[i] = i + 1
+		if i > 0 && entry.Score == b.entries[i-1].Score && entry.Timestamp == b.entries[i-1].Timestamp {
+			b.ranks[i] = b.ranks[i-1]
+		}
+
+		b.index[string(entry.Uuid)] = i
+	}
+}
+
+func (b *leaderboard) ranking(i int) defs.DailyRanking {
+	entry := b.entries[i]
+	return defs.DailyRanking{Rank: b.ranks[i], Username: entry.Username, Score: entry.Score, Wave: entry.Wave}
+}
+
+func (b *leaderboard) page(page, pageSize int) []defs.DailyRanking {
+	var rankings []defs.DailyRanking
+	for i := (page - 1) * pageSize; i < len(b.entries) && i < page*pageSize; i++ {
+		rankings = append(rankings, b.ranking(i))
+	}
+
+	return rankings
+}
+
+func (b *leaderboard) around(uuid []byte, neighbors int) (defs.PlayerRanking, bool) {
+	position, ok := b.index[string(uuid)]
+	if !ok {
+		return defs.PlayerRanking{}, false
+	}
+
+	ranking := defs.PlayerRanking{Rankings: []defs.DailyRanking{}}
+	for i := max(position-neighbors, 0); i < len(b.entries) && i <= position+neighbors; i++ {
+		entry := b.ranking(i)
+		if i == position {
+			entry.Self = true
+			ranking.Rank, ranking.Score, ranking.Wave = entry.Rank, entry.Score, entry.Wave
+		}
+
+		ranking.Rankings = append(ranking.Rankings, entry)
+	}
+
+	return ranking, true
+}
+
+// leaderboardCategories are the categories cached for the current period.
+// All-time rankings are paged from the database instead, as caching them would
+// hold every account that ever played a daily run in memory.
+var leaderboardCategories = []int{defs.RankingCategoryDaily, defs.RankingCategoryWeekly, defs.RankingCategoryMonthly}
+
+type leaderboardCache struct {
+	mu      sync.RWMutex
+	date    string
+	builtAt time.Time
+	dirty   bool
+	version uint64
+	boards  map[int]*leaderboard
+}
+
+var leaderboards leaderboardCache
+
+type LeaderboardStore interface {
+	FetchLeaderboard(category int, from, to string) ([]*defs.LeaderboardEntry, error)
+}
+
+// rebuild loads every cached category for date from the database. The caller
+// must hold the write lock.
+func (c *leaderboardCache) rebuild(store LeaderboardStore, date string) error {
+	boards := make(map[int]*leaderboard, len(leaderboardCategories))
+	for _, category := range leaderboardCategories {
+		from, to, err := rankingWindow(date, category)
+		if err != nil {
+			return err
+		}
+
+		entries, err := store.FetchLeaderboard(category, from, to)
+		if err != nil {
+			return apierror.Internalf("failed to fetch leaderboard: %s", err)
+		}
+
+		boards[category] = newLeaderboard(entries)
+	}
+
+	c.date = date
+	c.boards = boards
+	c.builtAt = time.Now()
+	c.dirty = false
+	c.version++
+
+	return nil
+}
+
+// read calls f with the board for category if date is the current period.
+// The cache is kept up to date by record, so it is only rebuilt after the
+// rollover or an invalidation. It reports whether the cache could serve the
+// request.
+func read[T LeaderboardStore](store T, date string, category int, f func(board *leaderboard, version string)) (bool, error) {
+	if date != Today() || !slices.Contains(leaderboardCategories, category) {
+		return false, nil
+	}
+
+	c := &leaderboards
+
+	c.mu.RLock()
+	fresh := !c.dirty && c.date == date
+	if fresh {
+		defer c.mu.RUnlock()
+	} else {
+		c.mu.RUnlock()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// another request may have rebuilt it while waiting for the lock
+		if c.dirty || c.date != date {
+			err := c.rebuild(store, date)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	f(c.boards[category], fmt.Sprintf("%x-%x", c.builtAt.UnixNano(), c.version))
+
+	return true, nil
+}
+
+// InvalidateLeaderboards makes the next read rebuild the cached leaderboards,
+// for changes that can't be applied incrementally such as bans and renames.
+func InvalidateLeaderboards() {
+	leaderboards.mu.Lock()
+	leaderboards.dirty = true
+	leaderboards.mu.Unlock()
+}
+
+// RebuildLeaderboards loads the leaderboards for the current period.
+func RebuildLeaderboards[T LeaderboardStore](store T) error {
+	leaderboards.mu.Lock()
+	defer leaderboards.mu.Unlock()
+
+	return leaderboards.rebuild(store, Today())
+}
+
+// record applies a daily run to the cached boards the same way
+// AddOrUpdateAccountDailyRun applies it to the database: the best score and
+// wave of the day are kept, and the period boards sum the daily bests.
+func (c *leaderboardCache) record(uuid []byte, username, date string, score, wave int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dirty || c.date != date || c.boards == nil {
+		return
+	}
+
+	now := time.Now().UTC().Format(time.DateTime)
+
+	daily := c.boards[defs.RankingCategoryDaily]
+
+	delta := score
+	improved := true
+	if i, ok := daily.index[string(uuid)]; ok {
+		entry := daily.entries[i]
+		delta = max(score-entry.Score, 0)
+		improved = score > entry.Score
+		entry.Score = max(entry.Score, score)
+		entry.Wave = max(entry.Wave, wave)
+		if improved {
+			entry.Timestamp = now
+		}
+	} else {
+		daily.entries = append(daily.entries, &defs.LeaderboardEntry{Uuid: uuid, Username: username, Score: score, Wave: wave, Timestamp: now})
+	}
+	daily.sort()
+
+	for _, category := range leaderboardCategories {
+		if category == defs.RankingCategoryDaily {
+			continue
+		}
+
+		board := c.boards[category]
+		if i, ok := board.index[string(uuid)]; ok {
+			entry := board.entries[i]
+			entry.Score += delta
+			if improved {
+				entry.Timestamp = now
+			}
+		} else {
+			board.entries = append(board.entries, &defs.LeaderboardEntry{Uuid: uuid, Username: username, Score: delta, Timestamp: now})
+		}
+		board.sort()
+	}
+
+	c.version++
+}
+
+// Interface for database operations needed for recording a daily run.
+type RecordRunStore interface {
+	AddOrUpdateAccountDailyRun(uuid []byte, date string, score int, wave int) error
+	FetchAccountDetails(uuid []byte) (defs.AccountDetails, error)
+}
+
+// AddOrUpdateAccountDailyRun records the account's run for date and applies
+// it to the cached leaderboards.
+func AddOrUpdateAccountDailyRun[T RecordRunStore](store T, uuid []byte, date string, score, wave int) error {
+	err := store.AddOrUpdateAccountDailyRun(uuid, date, score, wave)
+	if err != nil {
+		return err
+	}
+
+	details, err := store.FetchAccountDetails(uuid)
+	if err != nil {
+		InvalidateLeaderboards()
+		return nil
+	}
+
+	// banned accounts are left out of the rankings
+	if !details.Banned {
+		leaderboards.record(uuid, details.Username, date, score, wave)
+	}
+
+	return nil
+}
+
+// pageCount is the number of pages of pageSize the board fills.
+func (b *leaderboard) pageCount(pageSize int) int {
+	return (len(b.entries) + pageSize - 1) / pageSize
+}
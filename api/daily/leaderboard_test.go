@@ -0,0 +1,197 @@
+package daily
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func entry(uuid string, score int, timestamp string) *defs.LeaderboardEntry {
+	return &defs.LeaderboardEntry{Uuid: []byte(uuid), Username: uuid, Score: score, Timestamp: timestamp}
+}
+
+func TestLeaderboardOrder(t *testing.T) {
+	board := newLeaderboard([]*defs.LeaderboardEntry{
+		entry("late", 500, "2025-01-05 12:00:00"),
+		entry("low", 100, "2025-01-01 00:00:00"),
+		entry("early", 500, "2025-01-03 08:00:00"),
+		entry("tiedB", 300, "2025-01-02 00:00:00"),
+		entry("tiedA", 300, "2025-01-02 00:00:00"),
+	})
+
+	expected := []struct {
+		username string
+		rank     int
+	}{
+		{"early", 1},
+		{"late", 2},
+		{"tiedA", 3},
+		{"tiedB", 3},
+		{"low", 5},
+	}
+
+	rankings := board.page(1, 10)
+	if len(rankings) != len(expected) {
+		t.Fatalf("expected %d rankings, got %d", len(expected), len(rankings))
+	}
+
+	for i, e := range expected {
+		if rankings[i].Username != e.username || rankings[i].Rank != e.rank {
+			t.Errorf("position %d: expected %s at rank %d, got %s at rank %d", i, e.username, e.rank, rankings[i].Username, rankings[i].Rank)
+		}
+	}
+
+	if count := board.pageCount(2); count != 3 {
+		t.Errorf("expected 3 pages of 2, got %d", count)
+	}
+	if page := board.page(3, 2); len(page) != 1 || page[0].Username != "low" {
+		t.Errorf("expected the last page to hold only low, got %v", page)
+	}
+}
+
+func TestLeaderboardAround(t *testing.T) {
+	board := newLeaderboard([]*defs.LeaderboardEntry{
+		entry("a", 500, "t"), entry("b", 400, "t"), entry("c", 300, "t"), entry("d", 200, "t"), entry("e", 100, "t"),
+	})
+
+	ranking, ok := board.around([]byte("a"), 2)
+	if !ok || ranking.Rank != 1 || len(ranking.Rankings) != 3 || !ranking.Rankings[0].Self {
+		t.Errorf("expected the top player with the 2 below, got %+v", ranking)
+	}
+
+	ranking, ok = board.around([]byte("c"), 1)
+	if !ok || ranking.Rank != 3 || ranking.Score != 300 || len(ranking.Rankings) != 3 || ranking.Rankings[0].Username != "b" || ranking.Rankings[2].Username != "d" {
+		t.Errorf("expected c between b and d, got %+v", ranking)
+	}
+
+	if _, ok = board.around([]byte("unranked"), 1); ok {
+		t.Errorf("expected an unranked player not to be found")
+	}
+}
+
+func TestLeaderboardRecord(t *testing.T) {
+	date := "2025-01-08"
+	cache := &leaderboardCache{date: date, boards: map[int]*leaderboard{}}
+	for _, category := range leaderboardCategories {
+		cache.boards[category] = newLeaderboard(nil)
+	}
+
+	// the weekly board already has a run from earlier in the week
+	cache.boards[defs.RankingCategoryWeekly] = newLeaderboard([]*defs.LeaderboardEntry{entry("ash", 1000, "2025-01-06 00:00:00")})
+
+	cache.record([]byte("ash"), "ash", date, 300, 10)
+	cache.record([]byte("ash"), "ash", date, 200, 12)
+	cache.record([]byte("misty"), "misty", date, 1200, 20)
+
+	daily := cache.boards[defs.RankingCategoryDaily].page(1, 10)
+	if len(daily) != 2 || daily[0].Username != "misty" || daily[1].Score != 300 || daily[1].Wave != 12 {
+		t.Errorf("expected the best score and wave of the day to be kept, got %+v", daily)
+	}
+
+	weekly := cache.boards[defs.RankingCategoryWeekly].page(1, 10)
+	if len(weekly) != 2 || weekly[0].Username != "ash" || weekly[0].Score != 1300 {
+		t.Errorf("expected the week to sum the daily bests, got %+v", weekly)
+	}
+
+	// misty reaches the same total later, so ranks below ash
+	weeklyBoard := cache.boards[defs.RankingCategoryWeekly]
+	weeklyBoard.entries[weeklyBoard.index["ash"]].Timestamp = "2025-01-08 00:00:00"
+	cache.record([]byte("misty"), "misty", date, 1300, 20)
+	weekly = cache.boards[defs.RankingCategoryWeekly].page(1, 10)
+	if weekly[0].Username != "ash" || weekly[1].Username != "misty" || weekly[1].Score != 1300 || weekly[1].Rank != 2 {
+		t.Errorf("expected ash to rank first for reaching the total first, got %+v", weekly)
+	}
+
+	cache.record([]byte("brock"), "brock", "2025-01-07", 5000, 50)
+	if _, ok := cache.boards[defs.RankingCategoryDaily].index["brock"]; ok {
+		t.Errorf("expected a run for another date not to be recorded")
+	}
+
+	cache.dirty = true
+	cache.record([]byte("brock"), "brock", date, 5000, 50)
+	if _, ok := cache.boards[defs.RankingCategoryDaily].index["brock"]; ok {
+		t.Errorf("expected an invalidated cache not to be updated")
+	}
+}
+
+type mockLeaderboardStore struct {
+	fetched []int
+}
+
+func (m *mockLeaderboardStore) FetchLeaderboard(category int, from, to string) ([]*defs.LeaderboardEntry, error) {
+	m.fetched = append(m.fetched, category)
+	return []*defs.LeaderboardEntry{entry("ash", 100, from+" 00:00:00")}, nil
+}
+
+func TestLeaderboardRead(t *testing.T) {
+	leaderboards = leaderboardCache{}
+	defer func() { leaderboards = leaderboardCache{} }()
+
+	store := &mockLeaderboardStore{}
+	serve := func(category int) bool {
+		cached, err := read(store, Today(), category, func(*leaderboard, string) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cached
+	}
+
+	if !serve(defs.RankingCategoryDaily) || len(store.fetched) != len(leaderboardCategories) {
+		t.Fatalf("expected the first read to build every cached category, fetched %v", store.fetched)
+	}
+
+	serve(defs.RankingCategoryWeekly)
+	leaderboards.record([]byte("misty"), "misty", Today(), 200, 10)
+	serve(defs.RankingCategoryDaily)
+	if len(store.fetched) != len(leaderboardCategories) {
+		t.Errorf("expected recorded runs not to rebuild the cache, fetched %v", store.fetched)
+	}
+
+	if serve(defs.RankingCategoryAllTime) || slices.Contains(store.fetched, defs.RankingCategoryAllTime) {
+		t.Errorf("expected all-time rankings not to be cached")
+	}
+
+	InvalidateLeaderboards()
+	serve(defs.RankingCategoryMonthly)
+	if len(store.fetched) != 2*len(leaderboardCategories) {
+		t.Errorf("expected an invalidation to rebuild the cache, fetched %v", store.fetched)
+	}
+}
+
+func TestRankingWindow(t *testing.T) {
+	defer func() { WeekStart = time.Sunday }()
+
+	tests := []struct {
+		date      string
+		category  int
+		weekStart time.Weekday
+		from      string
+	}{
+		{"2025-01-08", defs.RankingCategoryDaily, time.Sunday, "2025-01-08"},
+		{"2025-01-08", defs.RankingCategoryWeekly, time.Sunday, "2025-01-05"},
+		{"2025-01-05", defs.RankingCategoryWeekly, time.Sunday, "2025-01-05"},
+		{"2025-01-08", defs.RankingCategoryWeekly, time.Monday, "2025-01-06"},
+		{"2025-01-05", defs.RankingCategoryWeekly, time.Monday, "2024-12-30"},
+		{"2025-01-08", defs.RankingCategoryWeekly, time.Thursday, "2025-01-02"},
+		{"2025-01-08", defs.RankingCategoryMonthly, time.Sunday, "2025-01-01"},
+		{"2025-01-08", defs.RankingCategoryAllTime, time.Sunday, allTimeStart},
+	}
+
+	for _, test := range tests {
+		WeekStart = test.weekStart
+		from, to, err := rankingWindow(test.date, test.category)
+		if err != nil {
+			t.Errorf("%s category %d: unexpected error: %v", test.date, test.category, err)
+			continue
+		}
+		if from != test.from || to != test.date {
+			t.Errorf("%s category %d starting %s: expected %s to %s, got %s to %s", test.date, test.category, test.weekStart, test.from, test.date, from, to)
+		}
+	}
+
+	if _, _, err := rankingWindow("2025-01-08", 9); err == nil {
+		t.Errorf("expected error for an unknown category, got nil")
+	}
+}
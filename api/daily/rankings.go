@@ -18,21 +18,192 @@
 package daily
 
 import (
-	"github.com/pagefaultgames/rogueserver/db"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
+const (
+	// allTimeStart is the start of the window used for all-time rankings.
+	allTimeStart = "1970-01-01"
+
+	RankingsDefaultPageSize = 10
+	NeighborsDefault        = 5
+	NeighborsMax            = 25
+)
+
+var (
+	// RankingsMaxPageSize caps the page size clients can request.
+	RankingsMaxPageSize = 50
+
+	// WeekStart is the first day of the week for weekly rankings.
+	WeekStart = time.Sunday
+)
+
+// ParseWeekday parses a day name such as "monday", as read from the
+// dailyweekstart env var.
+func ParseWeekday(value string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(value, day.String()) {
+			return day, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid weekday %q", value)
+}
+
 // Interface for database operations needed for fetching rankings.
 type RankingsStore interface {
-	FetchRankings(category, page int) ([]defs.DailyRanking, error)
+	FetchRankings(category int, from, to string, page, pageSize int) ([]defs.DailyRanking, error)
+	LeaderboardStore
+}
+
+// /daily/rankings - fetch the rankings of the given category for the period
+// containing date. The current period is served from the leaderboard cache,
+// in which case version identifies the state of the board.
+func Rankings[T RankingsStore](store T, date string, category, page, pageSize int) (rankings []defs.DailyRanking, version string, err error) {
+	from, to, err := rankingWindow(date, category)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pageSize, err = validatePage(page, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cached, err := read(store, date, category, func(board *leaderboard, boardVersion string) {
+		rankings, version = board.page(page, pageSize), boardVersion
+	})
+	if err != nil || cached {
+		return rankings, version, err
+	}
+
+	rankings, err = store.FetchRankings(category, from, to, page, pageSize)
+	if err != nil {
+		return rankings, "", err
+	}
+
+	return rankings, "", nil
+}
+
+// validatePage checks the requested page and returns the page size to use.
+func validatePage(page, pageSize int) (int, error) {
+	if page < 1 {
+		return 0, apierror.ErrInvalidPage
+	}
+
+	if pageSize == 0 {
+		return RankingsDefaultPageSize, nil
+	}
+
+	if pageSize < 1 || pageSize > RankingsMaxPageSize {
+		return 0, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", RankingsMaxPageSize)
+	}
+
+	return pageSize, nil
 }
 
-// /daily/rankings - fetch daily rankings
-func Rankings[T RankingsStore](store T, category, page int) ([]defs.DailyRanking, error) {
-	rankings, err := db.Store.FetchRankings(category, page)
+// Interface for database operations needed for fetching a player's ranking.
+type PlayerRankingStore interface {
+	FetchPlayerRanking(category int, from, to string, uuid []byte, neighbors int) (defs.PlayerRanking, error)
+	LeaderboardStore
+}
+
+// /daily/rankings/me - fetch the player's ranking along with the players
+// ranked directly above and below them
+func PlayerRanking[T PlayerRankingStore](store T, uuid []byte, date string, category, neighbors int) (defs.PlayerRanking, error) {
+	from, to, err := rankingWindow(date, category)
+	if err != nil {
+		return defs.PlayerRanking{}, err
+	}
+
+	if neighbors < 0 || neighbors > NeighborsMax {
+		return defs.PlayerRanking{}, apierror.ErrInvalidParameter.Withf("neighbors must be between 0 and %d", NeighborsMax)
+	}
+
+	var ranking defs.PlayerRanking
+	var found bool
+	cached, err := read(store, date, category, func(board *leaderboard, _ string) {
+		ranking, found = board.around(uuid, neighbors)
+	})
+	if err != nil {
+		return ranking, err
+	}
+
+	if cached {
+		if !found {
+			return ranking, sql.ErrNoRows
+		}
+
+		return ranking, nil
+	}
+
+	return store.FetchPlayerRanking(category, from, to, uuid, neighbors)
+}
+
+// rankingWindow returns the first and last dates of the category's period
+// containing date.
+func rankingWindow(date string, category int) (string, string, error) {
+	day, err := time.Parse(time.DateOnly, date)
 	if err != nil {
-		return rankings, err
+		return "", "", apierror.ErrInvalidDate
+	}
+
+	var from time.Time
+	switch category {
+	case defs.RankingCategoryDaily:
+		from = day
+	case defs.RankingCategoryWeekly:
+		from = day.AddDate(0, 0, -(int(day.Weekday()-WeekStart)+7)%7)
+	case defs.RankingCategoryMonthly:
+		from = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case defs.RankingCategoryAllTime:
+		return allTimeStart, date, nil
+	default:
+		return "", "", apierror.ErrInvalidParameter.Withf("unknown category %d", category)
+	}
+
+	return from.Format(time.DateOnly), date, nil
+}
+
+// Interface for database operations needed for resolving a rankings date.
+type RankingsDateStore interface {
+	FetchDailyRunDate(seed string) (string, error)
+}
+
+// RankingsDate resolves the date rankings are requested for, from either a
+// date or the seed of a past daily run. It defaults to today.
+func RankingsDate[T RankingsDateStore](store T, date, seed string) (string, error) {
+	if date != "" && seed != "" {
+		return "", apierror.ErrInvalidParameter.Withf("only one of date and seed can be given")
+	}
+
+	if seed != "" {
+		date, err := store.FetchDailyRunDate(seed)
+		if err != nil {
+			return "", apierror.ErrDailyRunNotFound.Withf("no daily run has seed %s", seed)
+		}
+
+		return date, nil
+	}
+
+	today := Today()
+	if date == "" {
+		return today, nil
+	}
+
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return "", apierror.ErrInvalidDate
+	}
+
+	if date > today {
+		return "", apierror.ErrInvalidDate.Withf("date is in the future")
 	}
 
-	return rankings, nil
+	return date, nil
 }
@@ -18,15 +18,34 @@
 package daily
 
 type RankingPageCountStore interface {
-	FetchRankingPageCount(category int) (int, error)
+	FetchRankingPageCount(category int, from, to string, pageSize int) (int, error)
+	LeaderboardStore
 }
 
-// /daily/rankingpagecount - fetch daily ranking page count
-func RankingPageCount[T RankingPageCountStore](store T, category int) (int, error) {
-	pageCount, err := store.FetchRankingPageCount(category)
+// /daily/rankingpagecount - fetch daily ranking page count. version is set
+// when served from the leaderboard cache, as for Rankings.
+func RankingPageCount[T RankingPageCountStore](store T, date string, category, pageSize int) (pageCount int, version string, err error) {
+	from, to, err := rankingWindow(date, category)
 	if err != nil {
-		return pageCount, err
+		return 0, "", err
 	}
 
-	return pageCount, nil
+	pageSize, err = validatePage(1, pageSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	cached, err := read(store, date, category, func(board *leaderboard, boardVersion string) {
+		pageCount, version = board.pageCount(pageSize), boardVersion
+	})
+	if err != nil || cached {
+		return pageCount, version, err
+	}
+
+	pageCount, err = store.FetchRankingPageCount(category, from, to, pageSize)
+	if err != nil {
+		return pageCount, "", err
+	}
+
+	return pageCount, "", nil
 }
@@ -0,0 +1,187 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package daily
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	ScheduleDefaultDays = 7
+	ScheduleMaxDays     = 30
+
+	eventNameMaxLength = 64
+	seedMaxLength      = 24
+)
+
+// Interface for database operations needed for fetching the schedule.
+type ScheduleStore interface {
+	FetchDailyRuns(from, to string) ([]defs.DailyScheduleEntry, error)
+	FetchDailyRunOverrides(from, to string) ([]defs.DailyScheduleEntry, error)
+	FetchEventRuns(from, to time.Time) ([]defs.EventRun, error)
+}
+
+// /daily/schedule - list the daily runs of the past days along with the
+// special runs scheduled for the upcoming days, and events in that window
+func Schedule[T ScheduleStore](store T, past, upcoming int) (defs.DailySchedule, error) {
+	if past < 0 || past > ScheduleMaxDays || upcoming < 0 || upcoming > ScheduleMaxDays {
+		return defs.DailySchedule{}, apierror.ErrInvalidParameter.Withf("days must be between 0 and %d", ScheduleMaxDays)
+	}
+
+	now := time.Now()
+	today := Date(now)
+
+	schedule := defs.DailySchedule{
+		Timezone: Location.String(),
+		Rollover: time.Time{}.Add(Rollover).Format("15:04"),
+		Today:    today.Format(time.DateOnly),
+	}
+
+	overrides, err := store.FetchDailyRunOverrides(today.AddDate(0, 0, 1).Format(time.DateOnly), today.AddDate(0, 0, upcoming).Format(time.DateOnly))
+	if err != nil {
+		return schedule, err
+	}
+
+	runs, err := store.FetchDailyRuns(today.AddDate(0, 0, -past).Format(time.DateOnly), schedule.Today)
+	if err != nil {
+		return schedule, err
+	}
+
+	// upcoming runs first, latest at the top, to match the past runs
+	for i := len(overrides) - 1; i >= 0; i-- {
+		schedule.Days = append(schedule.Days, overrides[i])
+	}
+	schedule.Days = append(schedule.Days, runs...)
+
+	events, err := store.FetchEventRuns(now.AddDate(0, 0, -past).UTC(), now.AddDate(0, 0, upcoming).UTC())
+	if err != nil {
+		return schedule, err
+	}
+
+	for i := range events {
+		starts, err := time.Parse(time.DateTime, events[i].Starts)
+		if err != nil {
+			return schedule, err
+		}
+
+		if now.Before(starts) {
+			events[i].Seed = ""
+		}
+	}
+
+	schedule.Events = events
+
+	return schedule, nil
+}
+
+// Interface for database operations needed for scheduling a daily run override.
+type OverrideStore interface {
+	AddDailyRunOverride(date, seed, name string, createdBy []byte) error
+}
+
+// /admin/daily/override - replace the seed of an upcoming daily run. An empty
+// seed picks a random one.
+func Override[T OverrideStore](store T, date, seed, name string, createdBy []byte) (string, error) {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return "", apierror.ErrInvalidDate
+	}
+
+	// today's run has already been recorded with its seed
+	if !day.After(Date(time.Now())) {
+		return "", apierror.ErrDailyRunNotPending.Withf("only upcoming daily runs can be overridden")
+	}
+
+	if len(name) > eventNameMaxLength {
+		return "", apierror.ErrInvalidParameter.Withf("name must be at most %d characters", eventNameMaxLength)
+	}
+
+	seed, err = validateSeed(seed)
+	if err != nil {
+		return "", err
+	}
+
+	err = store.AddDailyRunOverride(date, seed, name, createdBy)
+	if err != nil {
+		return "", apierror.Internalf("failed to add override: %s", err)
+	}
+
+	return seed, nil
+}
+
+// Interface for database operations needed for removing a daily run override.
+type RemoveOverrideStore interface {
+	FetchDailyRunOverride(date string) (string, error)
+	RemoveDailyRunOverride(date string) (bool, error)
+}
+
+// /admin/daily/restore - drop the override of an upcoming daily run so it
+// uses the regular seed again
+func RemoveOverride[T RemoveOverrideStore](store T, date string) (string, error) {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return "", apierror.ErrInvalidDate
+	}
+
+	if !day.After(Date(time.Now())) {
+		return "", apierror.ErrDailyRunNotPending.Withf("only upcoming daily runs can be restored")
+	}
+
+	seed, err := store.FetchDailyRunOverride(date)
+	if err != nil {
+		return "", apierror.ErrOverrideNotFound.Withf("no override is scheduled for %s", date)
+	}
+
+	_, err = store.RemoveDailyRunOverride(date)
+	if err != nil {
+		return "", apierror.Internalf("failed to remove override: %s", err)
+	}
+
+	return seed, nil
+}
+
+// validateSeed checks an admin provided seed fits the seed columns, or
+// generates one shaped like a regular daily seed if it is empty.
+func validateSeed(seed string) (string, error) {
+	if seed == "" {
+		newSeed := make([]byte, 16)
+		_, err := rand.Read(newSeed)
+		if err != nil {
+			return "", apierror.Internalf("failed to generate seed: %s", err)
+		}
+
+		return base64.StdEncoding.EncodeToString(newSeed), nil
+	}
+
+	if len(seed) > seedMaxLength {
+		return "", apierror.ErrInvalidSeed.Withf("seed must be at most %d characters", seedMaxLength)
+	}
+
+	for _, c := range seed {
+		if c < '!' || c > '~' {
+			return "", apierror.ErrInvalidSeed.Withf("seed must be printable ascii")
+		}
+	}
+
+	return seed, nil
+}
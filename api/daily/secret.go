@@ -0,0 +1,218 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package daily
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	secondsPerDay = 60 * 60 * 24
+
+	secretSize = 32
+	// seedSize keeps seeds at 24 base64 characters, the width of the seed columns
+	seedSize = 16
+)
+
+var (
+	// ConfigSecrets are the seed secrets set through the dailysecrets env
+	// var. When set they are used instead of the secrets stored in the
+	// database, and rotation has to be done through config.
+	ConfigSecrets []defs.DailySeedSecret
+
+	secrets   []defs.DailySeedSecret
+	secretsMu sync.RWMutex
+)
+
+// ParseSecrets parses a comma separated list of `2006-01-02:base64 secret`
+// pairs, as read from the dailysecrets env var. Each secret is used for the
+// days from its date until the next secret's date.
+func ParseSecrets(value string) ([]defs.DailySeedSecret, error) {
+	var parsed []defs.DailySeedSecret
+
+	for i, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		activeFrom, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid daily seed secret %d", i+1)
+		}
+
+		_, err := time.Parse(time.DateOnly, activeFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date for daily seed secret %d", i+1)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) < secretSize {
+			return nil, fmt.Errorf("daily seed secret %d must be at least %d bytes of base64", i+1, secretSize)
+		}
+
+		parsed = append(parsed, defs.DailySeedSecret{Version: i + 1, ActiveFrom: activeFrom, Secret: secret})
+	}
+
+	return parsed, nil
+}
+
+type loadSecretsStore interface {
+	FetchDailySeedSecrets() ([]defs.DailySeedSecret, error)
+	TryAddInitialDailySeedSecret(secret []byte) error
+}
+
+// loadSecrets loads the seed secrets from config, or else from the database so
+// that every instance derives the same seeds. The first instance to start
+// against an empty database creates the initial secret, carrying over a legacy
+// secret.key file if there is one.
+func loadSecrets[T loadSecretsStore](store T) error {
+	loaded := ConfigSecrets
+	if len(loaded) == 0 {
+		var err error
+		loaded, err = store.FetchDailySeedSecrets()
+		if err != nil {
+			return apierror.Internalf("failed to fetch daily seed secrets: %s", err)
+		}
+
+		if len(loaded) == 0 {
+			secret, err := os.ReadFile("secret.key")
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return apierror.Internalf("failed to read daily seed secret: %s", err)
+				}
+
+				secret = make([]byte, secretSize)
+				_, err = rand.Read(secret)
+				if err != nil {
+					return apierror.Internalf("failed to generate daily seed secret: %s", err)
+				}
+			}
+
+			err = store.TryAddInitialDailySeedSecret(secret)
+			if err != nil {
+				return apierror.Internalf("failed to add daily seed secret: %s", err)
+			}
+
+			loaded, err = store.FetchDailySeedSecrets()
+			if err != nil {
+				return apierror.Internalf("failed to fetch daily seed secrets: %s", err)
+			}
+		}
+	}
+
+	sorted := make([]defs.DailySeedSecret, len(loaded))
+	copy(sorted, loaded)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ActiveFrom != sorted[j].ActiveFrom {
+			return sorted[i].ActiveFrom < sorted[j].ActiveFrom
+		}
+
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	secretsMu.Lock()
+	secrets = sorted
+	secretsMu.Unlock()
+
+	return nil
+}
+
+// secretFor returns the secret in effect on date.
+func secretFor(date time.Time) ([]byte, error) {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	day := date.Format(time.DateOnly)
+
+	var secret []byte
+	for _, s := range secrets {
+		if s.ActiveFrom > day {
+			break
+		}
+
+		secret = s.Secret
+	}
+
+	if secret == nil {
+		return nil, apierror.ErrSecretNotFound.Withf("no daily seed secret is active on %s", day)
+	}
+
+	return secret, nil
+}
+
+func deriveSeed(seedTime time.Time) ([]byte, error) {
+	secret, err := secretFor(seedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	day := make([]byte, 8)
+	binary.BigEndian.PutUint64(day, uint64(seedTime.Unix()/secondsPerDay))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(day)
+
+	return mac.Sum(nil)[:seedSize], nil
+}
+
+// Interface for database operations needed for rotating the seed secret.
+type RotateSecretStore interface {
+	AddDailySeedSecret(secret []byte, activeFrom string) (int, error)
+	loadSecretsStore
+}
+
+// /admin/daily/rotateSecret - add a new seed secret taking effect from the
+// next daily run, so no seed that has already been published changes
+func RotateSecret[T RotateSecretStore](store T) (defs.DailySeedSecret, error) {
+	if len(ConfigSecrets) > 0 {
+		return defs.DailySeedSecret{}, apierror.ErrSecretsFromConfig
+	}
+
+	secret := make([]byte, secretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return defs.DailySeedSecret{}, apierror.Internalf("failed to generate daily seed secret: %s", err)
+	}
+
+	activeFrom := Date(time.Now()).AddDate(0, 0, 1).Format(time.DateOnly)
+
+	version, err := store.AddDailySeedSecret(secret, activeFrom)
+	if err != nil {
+		return defs.DailySeedSecret{}, apierror.Internalf("failed to add daily seed secret: %s", err)
+	}
+
+	err = loadSecrets(store)
+	if err != nil {
+		return defs.DailySeedSecret{}, err
+	}
+
+	return defs.DailySeedSecret{Version: version, ActiveFrom: activeFrom}, nil
+}
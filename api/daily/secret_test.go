@@ -0,0 +1,67 @@
+package daily
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestDeriveSeed(t *testing.T) {
+	first := bytes.Repeat([]byte{1}, secretSize)
+	second := bytes.Repeat([]byte{2}, secretSize)
+
+	parsed, err := ParseSecrets("2024-01-01:" + base64.StdEncoding.EncodeToString(first) + ", 2024-06-01:" + base64.StdEncoding.EncodeToString(second))
+	if err != nil {
+		t.Fatalf("failed to parse secrets: %v", err)
+	}
+
+	secrets = parsed
+	defer func() { secrets = nil }()
+
+	t.Run("NoSecretActive", func(t *testing.T) {
+		_, err := deriveSeed(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+		if err == nil {
+			t.Errorf("expected error for a date before the first secret, got nil")
+		}
+	})
+	t.Run("SeedLength", func(t *testing.T) {
+		seed, err := deriveSeed(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("expected success, got error: %v", err)
+		}
+		if encoded := base64.StdEncoding.EncodeToString(seed); len(encoded) != 24 {
+			t.Errorf("expected a 24 character seed, got %q", encoded)
+		}
+	})
+	t.Run("Rotation", func(t *testing.T) {
+		before, after := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		secrets = parsed[:1]
+		oldBefore, _ := deriveSeed(before)
+		oldAfter, _ := deriveSeed(after)
+
+		secrets = parsed
+		newBefore, _ := deriveSeed(before)
+		newAfter, _ := deriveSeed(after)
+
+		if !bytes.Equal(oldBefore, newBefore) {
+			t.Errorf("expected seed before the rotation date to be unchanged")
+		}
+		if bytes.Equal(oldAfter, newAfter) {
+			t.Errorf("expected the new secret to be used from its active date")
+		}
+	})
+}
+
+func TestParseSecrets(t *testing.T) {
+	if _, err := ParseSecrets("2024-01-01:" + base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Errorf("expected error for a short secret, got nil")
+	}
+	if _, err := ParseSecrets("yesterday:" + base64.StdEncoding.EncodeToString(make([]byte, secretSize))); err == nil {
+		t.Errorf("expected error for an invalid date, got nil")
+	}
+	if parsed, err := ParseSecrets(""); err != nil || len(parsed) != 0 {
+		t.Errorf("expected no secrets for an empty value, got %v, %v", parsed, err)
+	}
+}
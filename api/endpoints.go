@@ -18,9 +18,10 @@
 package api
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -30,8 +31,11 @@ import (
 	"time"
 
 	"github.com/pagefaultgames/rogueserver/api/account"
+	"github.com/pagefaultgames/rogueserver/api/admin"
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/api/daily"
 	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/api/stats"
 	"github.com/pagefaultgames/rogueserver/db"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
@@ -66,12 +70,7 @@ func handleAccountInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var hasAdminRole bool
-	if discordId != "" {
-		hasAdminRole, _ = account.Discord.IsUserDiscordAdmin(discordId, account.DiscordGuildID)
-	}
-
-	response, err := account.Info(db.Store, username, discordId, googleId, uuid, hasAdminRole)
+	response, err := account.Info(db.Store, username, discordId, googleId, uuid)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
@@ -129,6 +128,89 @@ func handleAccountChangePW(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, r, response)
 }
 
+func handleAccountChangeUsername(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = account.ChangeUsername(db.Store, uuid, r.PostFormValue("username"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	daily.InvalidateLeaderboards()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAccountStats(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := account.Profile(db.Store, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, profile)
+}
+
+func handleAccountProfile(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	private, err := strconv.ParseBool(r.PostFormValue("private"))
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert private: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	err = account.SetProfilePrivate(db.Store, uuid, private)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	target, err := db.Store.FetchUUIDFromUsername(r.PathValue("username"))
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	// profiles are public, but owners can still see their own private one
+	viewer, _ := uuidFromRequest(r)
+
+	profile, err := account.PublicProfile(db.Store, target, viewer)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		case errors.Is(err, apierror.ErrProfilePrivate):
+			httpError(w, r, err, http.StatusForbidden)
+		default:
+			httpError(w, r, err, http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	writeJSON(w, r, profile)
+}
+
 func handleAccountLogout(w http.ResponseWriter, r *http.Request) {
 	token, err := tokenFromRequest(r)
 	if err != nil {
@@ -146,46 +228,139 @@ func handleAccountLogout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// game
-func handleGameTitleStats(w http.ResponseWriter, r *http.Request) {
-	stats := defs.TitleStats{
-		PlayerCount: playerCount,
-		BattleCount: battleCount,
+func handleAccountBanAppeal(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = account.AppealBan(db.Store, uuid, r.PostFormValue("appeal"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
 	}
 
-	writeJSON(w, r, stats)
+	w.WriteHeader(http.StatusOK)
 }
 
-func handleGameClassicSessionCount(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, classicSessionCount)
+func handleAccountExport(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	writeAccountExport(w, r, uuid)
 }
 
-func handleSession(w http.ResponseWriter, r *http.Request) {
+// writeAccountExport builds the export in memory first, so that a failure
+// halfway through is still reported as an error rather than a truncated zip.
+func writeAccountExport(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username, err := db.Store.FetchUsernameFromUUID(uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	err = account.Export(db.Store, uuid, buf)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "pokerogue-"+username+".zip"))
+	w.Write(buf.Bytes())
+}
+
+type AccountDeleteResponse struct {
+	DeletionDate string `json:"deletionDate"`
+}
+
+func handleAccountDelete(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	scheduled, err := account.RequestDeletion(db.Store, uuid, r.PostFormValue("password"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, AccountDeleteResponse{DeletionDate: scheduled.Format(time.DateTime)})
+}
+
+func handleAccountDeleteCancel(w http.ResponseWriter, r *http.Request) {
 	uuid, err := uuidFromRequest(r)
 	if err != nil {
 		httpError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	slot, err := strconv.Atoi(r.URL.Query().Get("slot"))
+	err = account.CancelDeletion(db.Store, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// game
+func handleGameTitleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, stats.Title())
+}
+
+func handleGameClassicSessionCount(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, stats.Title().ClassicSessionCount)
+}
+
+func handleGameStats(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := stats.Series(db.Store, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
 	if err != nil {
 		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	if slot < 0 || slot >= defs.SessionSlotCount {
-		httpError(w, r, fmt.Errorf("slot id %d out of range", slot), http.StatusBadRequest)
+	writeJSON(w, r, snapshots)
+}
+
+func handleGameDexStats(w http.ResponseWriter, r *http.Request) {
+	dexStats, err := stats.DexStats(db.Store)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, dexStats)
+}
+
+func handleSession(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	slot, err := parseSlot(r.URL.Query().Get("slot"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	if !r.URL.Query().Has("clientSessionId") {
-		httpError(w, r, fmt.Errorf("missing clientSessionId"), http.StatusBadRequest)
+		httpError(w, r, apierror.ErrMissingClientSessionId, http.StatusBadRequest)
 		return
 	}
 
 	err = db.Store.UpdateActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
 	if err != nil {
-		httpError(w, r, fmt.Errorf("failed to update active session: %s", err), http.StatusBadRequest)
+		httpError(w, r, fmt.Errorf("failed to update active session: %s", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -193,11 +368,6 @@ func handleSession(w http.ResponseWriter, r *http.Request) {
 	case "get":
 		save, err := savedata.GetSession(db.Store, uuid, slot)
 		if err != nil {
-			if errors.Is(err, savedata.ErrSaveNotExist) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-
 			httpError(w, r, err, http.StatusInternalServerError)
 			return
 		}
@@ -205,47 +375,28 @@ func handleSession(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, r, save)
 	case "update":
 		var session defs.SessionSaveData
-		err = json.NewDecoder(r.Body).Decode(&session)
+		err = decodeBody(w, r, &session)
 		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to decode request body: %s", err), http.StatusBadRequest)
+			httpError(w, r, err, http.StatusBadRequest)
 			return
 		}
 
-		existingSave, err := savedata.GetSession(db.Store, uuid, slot)
+		err = saveSession(uuid, slot, session)
 		if err != nil {
-			if !errors.Is(err, savedata.ErrSaveNotExist) {
-				httpError(w, r, fmt.Errorf("failed to retrieve session save data: %s", err), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			if existingSave.Seed == session.Seed && existingSave.WaveIndex > session.WaveIndex {
-				httpError(w, r, fmt.Errorf("session out of date: existing wave index is greater"), http.StatusBadRequest)
-				return
-			}
-		}
-
-		err = savedata.UpdateSession(db.Store, uuid, slot, session)
-		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to put session data: %s", err), http.StatusInternalServerError)
+			httpError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 	case "clear":
 		var session defs.SessionSaveData
-		err = json.NewDecoder(r.Body).Decode(&session)
-		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to decode request body: %s", err), http.StatusBadRequest)
-			return
-		}
-
-		seed, err := db.Store.GetDailyRunSeed()
+		err = decodeBody(w, r, &session)
 		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, err, http.StatusBadRequest)
 			return
 		}
 
-		resp, err := savedata.Clear(db.Store, uuid, slot, seed, session)
+		resp, err := clearSession(uuid, slot, session)
 		if err != nil {
 			httpError(w, r, err, http.StatusInternalServerError)
 			return
@@ -274,128 +425,89 @@ func handleSession(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-type CombinedSaveData struct {
-	System          defs.SystemSaveData  `json:"system"`
-	Session         defs.SessionSaveData `json:"session"`
-	SessionSlotId   int                  `json:"sessionSlotId"`
-	ClientSessionId string               `json:"clientSessionId"`
-}
-
-// TODO wrap this in a transaction
-func handleUpdateAll(w http.ResponseWriter, r *http.Request) {
+func handleSessionHistory(w http.ResponseWriter, r *http.Request) {
 	uuid, err := uuidFromRequest(r)
 	if err != nil {
 		httpError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	var data CombinedSaveData
-	err = json.NewDecoder(r.Body).Decode(&data)
-	if err != nil {
-		httpError(w, r, fmt.Errorf("failed to decode request body: %s", err), http.StatusBadRequest)
-		return
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
 	}
 
-	if data.ClientSessionId == "" {
-		httpError(w, r, fmt.Errorf("missing clientSessionId"), http.StatusBadRequest)
-		return
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
 	}
 
-	active, err := db.Store.IsActiveSession(uuid, data.ClientSessionId)
+	history, err := savedata.History(db.Store, uuid, page, pageSize)
 	if err != nil {
-		httpError(w, r, fmt.Errorf("failed to check active session: %s", err), http.StatusBadRequest)
+		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	if !active {
-		httpError(w, r, fmt.Errorf("session out of date: not active"), http.StatusBadRequest)
-		return
-	}
+	writeJSON(w, r, history)
+}
 
-	storedTrainerId, storedSecretId, err := db.Store.FetchTrainerIds(uuid)
+func handleSessionHistoryRun(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
 	if err != nil {
-		httpError(w, r, err, http.StatusInternalServerError)
+		httpError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	if storedTrainerId > 0 || storedSecretId > 0 {
-		if data.System.TrainerId != storedTrainerId || data.System.SecretId != storedSecretId {
-			httpError(w, r, fmt.Errorf("session out of date: stored trainer or secret ID does not match"), http.StatusBadRequest)
-			return
-		}
-	} else {
-		err = db.Store.UpdateTrainerIds(data.System.TrainerId, data.System.SecretId, uuid)
-		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
-			return
-		}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert id: %s", err), http.StatusBadRequest)
+		return
 	}
 
-	oldSystem, err := savedata.GetSystem(db.Store, uuid)
+	run, err := savedata.HistoryRun(db.Store, uuid, id)
 	if err != nil {
-		if !errors.Is(err, savedata.ErrSaveNotExist) {
-			httpError(w, r, fmt.Errorf("failed to retrieve playtime: %s", err), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		playtime, ok := data.System.GameStats.(map[string]interface{})["playTime"].(float64)
-		if !ok {
-			httpError(w, r, fmt.Errorf("no playtime found"), http.StatusBadRequest)
-			return
-		}
-
-		oldPlaytime, ok := oldSystem.GameStats.(map[string]interface{})["playTime"].(float64)
-		if !ok {
-			httpError(w, r, fmt.Errorf("no playtime found"), http.StatusBadRequest)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpError(w, r, fmt.Errorf("run does not exist"), http.StatusNotFound)
 			return
 		}
 
-		if playtime < oldPlaytime {
-			httpError(w, r, fmt.Errorf("session out of date: existing playtime is greater"), http.StatusBadRequest)
-			return
-		}
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
 
-		minVerCmp, err := savedata.CompareGameVersion("1.12.0.1", data.System.GameVersion)
-		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to compare versions: %s", err), http.StatusBadRequest)
-			return
-		}
-		if minVerCmp > 0 {
-			httpError(w, r, fmt.Errorf("session out of date: save version below minimum game version"), http.StatusBadRequest)
-			return
-		}
+	writeJSON(w, r, run)
+}
 
-		saveVerCmp, err := savedata.CompareGameVersion(oldSystem.GameVersion, data.System.GameVersion)
-		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to compare versions: %s", err), http.StatusBadRequest)
-			return
-		}
-		if saveVerCmp > 0 {
-			httpError(w, r, fmt.Errorf("session out of date: existing version is greater"), http.StatusBadRequest)
-			return
-		}
-	}
+type CombinedSaveData struct {
+	System          defs.SystemSaveData  `json:"system"`
+	Session         defs.SessionSaveData `json:"session"`
+	SessionSlotId   int                  `json:"sessionSlotId"`
+	ClientSessionId string               `json:"clientSessionId"`
+}
 
-	existingSave, err := savedata.GetSession(db.Store, uuid, data.SessionSlotId)
+func handleUpdateAll(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
 	if err != nil {
-		if !errors.Is(err, savedata.ErrSaveNotExist) {
-			httpError(w, r, fmt.Errorf("failed to retrieve session save data: %s", err), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		if existingSave.Seed == data.Session.Seed && existingSave.WaveIndex > data.Session.WaveIndex {
-			httpError(w, r, fmt.Errorf("session out of date: existing wave index is greater"), http.StatusBadRequest)
-			return
-		}
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
 	}
 
-	err = savedata.Update(db.Store, uuid, data.SessionSlotId, data.Session)
+	var data CombinedSaveData
+	err = decodeBody(w, r, &data)
 	if err != nil {
-		httpError(w, r, err, http.StatusInternalServerError)
+		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	err = savedata.Update(db.Store, uuid, 0, data.System)
+	err = saveAll(uuid, data)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
@@ -417,123 +529,53 @@ func handleSystem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !r.URL.Query().Has("clientSessionId") {
-		httpError(w, r, fmt.Errorf("missing clientSessionId"), http.StatusBadRequest)
+		httpError(w, r, apierror.ErrMissingClientSessionId, http.StatusBadRequest)
 		return
 	}
 
-	active, err := db.Store.IsActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
-	if err != nil {
-		httpError(w, r, fmt.Errorf("failed to check active session: %s", err), http.StatusBadRequest)
-		return
-	}
+	clientSessionId := r.URL.Query().Get("clientSessionId")
 
 	switch r.PathValue("action") {
 	case "get":
-		if !active {
-			err = db.Store.UpdateActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
-			if err != nil {
-				httpError(w, r, fmt.Errorf("failed to update active session: %s", err), http.StatusBadRequest)
-				return
-			}
+		err = db.Store.UpdateActiveSession(uuid, clientSessionId)
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to update active session: %s", err), http.StatusInternalServerError)
+			return
 		}
 
 		save, err := savedata.GetSystem(db.Store, uuid)
 		if err != nil {
-			if errors.Is(err, savedata.ErrSaveNotExist) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-
-			httpError(w, r, fmt.Errorf("failed to get system save data: %s", err), http.StatusInternalServerError)
+			httpError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
 		writeJSON(w, r, save)
 	case "update":
-		if !active {
-			httpError(w, r, fmt.Errorf("session out of date: not active"), http.StatusBadRequest)
+		err = checkActiveSession(uuid, clientSessionId)
+		if err != nil {
+			httpError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
 		var system defs.SystemSaveData
-		err = json.NewDecoder(r.Body).Decode(&system)
+		err = decodeBody(w, r, &system)
 		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to decode request body: %s", err), http.StatusBadRequest)
+			httpError(w, r, err, http.StatusBadRequest)
 			return
 		}
 
-		oldSystem, err := savedata.GetSystem(db.Store, uuid)
-		if err != nil {
-			if !errors.Is(err, savedata.ErrSaveNotExist) {
-				httpError(w, r, fmt.Errorf("failed to retrieve playtime: %s", err), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			playtime, ok := system.GameStats.(map[string]interface{})["playTime"].(float64)
-			if !ok {
-				httpError(w, r, fmt.Errorf("no playtime found"), http.StatusBadRequest)
-				return
-			}
-
-			oldPlaytime, ok := oldSystem.GameStats.(map[string]interface{})["playTime"].(float64)
-			if !ok {
-				httpError(w, r, fmt.Errorf("no playtime found"), http.StatusBadRequest)
-				return
-			}
-
-			if playtime < oldPlaytime {
-				httpError(w, r, fmt.Errorf("session out of date: existing playtime is greater"), http.StatusBadRequest)
-				return
-			}
-
-			minVerCmp, err := savedata.CompareGameVersion("1.12.0.1", system.GameVersion)
-			if err != nil {
-				httpError(w, r, fmt.Errorf("failed to compare versions: %s", err), http.StatusBadRequest)
-				return
-			}
-			if minVerCmp > 0 {
-				httpError(w, r, fmt.Errorf("session out of date: save version below minimum game version"), http.StatusBadRequest)
-				return
-			}
-
-			saveVerCmp, err := savedata.CompareGameVersion(oldSystem.GameVersion, system.GameVersion)
-			if err != nil {
-				httpError(w, r, fmt.Errorf("failed to compare versions: %s", err), http.StatusBadRequest)
-				return
-			}
-			if saveVerCmp > 0 {
-				httpError(w, r, fmt.Errorf("session out of date: existing version is greater"), http.StatusBadRequest)
-				return
-			}
-		}
-
-		err = savedata.UpdateSystem(db.Store, uuid, system)
+		err = saveSystem(uuid, system)
 		if err != nil {
-			httpError(w, r, fmt.Errorf("failed to put system data: %s", err), http.StatusInternalServerError)
+			httpError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 	case "verify":
-		response := SystemVerifyResponse{
-			Valid: active,
-		}
-
-		// not valid, send server state
-		if !active {
-			err := db.Store.UpdateActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
-			if err != nil {
-				httpError(w, r, fmt.Errorf("failed to update active session: %s", err), http.StatusBadRequest)
-				return
-			}
-
-			storedSaveData, err := db.Store.ReadSystemSaveData(uuid)
-			if err != nil {
-				httpError(w, r, fmt.Errorf("failed to read session save data: %s", err), http.StatusInternalServerError)
-				return
-			}
-
-			response.SystemData = storedSaveData
+		response, err := verifySystem(uuid, clientSessionId)
+		if err != nil {
+			httpError(w, r, err, http.StatusInternalServerError)
+			return
 		}
 
 		writeJSON(w, r, response)
@@ -551,14 +593,81 @@ func handleSystem(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Interface providing database operations needed for getting daily seed.
+// clearStore routes the daily runs recorded while clearing a session through
+// the leaderboard cache.
+type clearStore struct {
+	savedata.ClearStore
+}
+
+func (s clearStore) AddOrUpdateAccountDailyRun(uuid []byte, date string, score int, wave int) error {
+	return daily.AddOrUpdateAccountDailyRun(db.Store, uuid, date, score, wave)
+}
+
+func handleStatRankings(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rankings, version, err := stats.Rankings(db.Store, r.URL.Query().Get("stat"), page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if notModified(w, r, version) {
+		return
+	}
+
+	writeJSON(w, r, rankings)
+}
+
+func handleStatRankingPageCount(w http.ResponseWriter, r *http.Request) {
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		var err error
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	count, version, err := stats.RankingPageCount(db.Store, r.URL.Query().Get("stat"), pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if notModified(w, r, version) {
+		return
+	}
+
+	fmt.Fprint(w, count)
+}
+
 type HandleDailySeedStore interface {
-	GetDailyRunSeed() (string, error)
+	GetDailyRunSeed(date string) (string, error)
 }
 
 // daily
 func handleDailySeed(w http.ResponseWriter, r *http.Request) {
-	seed, err := db.Store.GetDailyRunSeed()
+	seed, err := db.Store.GetDailyRunSeed(daily.Today())
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
@@ -569,6 +678,7 @@ func handleDailySeed(w http.ResponseWriter, r *http.Request) {
 
 // Interface for database operations needed for getting daily rankings.
 type HandleDailyRankingsStore interface {
+	daily.RankingsDateStore
 	daily.RankingsStore
 }
 
@@ -593,23 +703,44 @@ func handleDailyRankings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rankings, err := daily.Rankings(db.Store, category, page)
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	date, err := daily.RankingsDate(db.Store, r.URL.Query().Get("date"), r.URL.Query().Get("seed"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	rankings, version, err := daily.Rankings(db.Store, date, category, page, pageSize)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
+	if notModified(w, r, version) {
+		return
+	}
+
 	writeJSON(w, r, rankings)
 }
 
 type HandleDailyRankingsPageCountStore interface {
+	daily.RankingsDateStore
 	daily.RankingPageCountStore
 }
 
 func handleDailyRankingPageCount(w http.ResponseWriter, r *http.Request) {
+	var err error
+
 	var category int
 	if r.URL.Query().Has("category") {
-		var err error
 		category, err = strconv.Atoi(r.URL.Query().Get("category"))
 		if err != nil {
 			httpError(w, r, fmt.Errorf("failed to convert category: %s", err), http.StatusBadRequest)
@@ -617,9 +748,165 @@ func handleDailyRankingPageCount(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	count, err := daily.RankingPageCount(db.Store, category)
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	date, err := daily.RankingsDate(db.Store, r.URL.Query().Get("date"), r.URL.Query().Get("seed"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	count, version, err := daily.RankingPageCount(db.Store, date, category, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(w, r, version) {
+		return
+	}
+
+	fmt.Fprint(w, count)
+}
+
+func handleDailyPlayerRanking(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var category int
+	if r.URL.Query().Has("category") {
+		category, err = strconv.Atoi(r.URL.Query().Get("category"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert category: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	neighbors := daily.NeighborsDefault
+	if r.URL.Query().Has("neighbors") {
+		neighbors, err = strconv.Atoi(r.URL.Query().Get("neighbors"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert neighbors: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	date, err := daily.RankingsDate(db.Store, r.URL.Query().Get("date"), r.URL.Query().Get("seed"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	ranking, err := daily.PlayerRanking(db.Store, uuid, date, category, neighbors)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpError(w, r, fmt.Errorf("no ranked run found"), http.StatusNotFound)
+			return
+		}
+
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, ranking)
+}
+
+func handleDailyHistory(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if r.URL.Query().Has("page") {
+		var err error
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := daily.History(db.Store, page)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, history)
+}
+
+func handleDailySchedule(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	past := daily.ScheduleDefaultDays
+	if r.URL.Query().Has("past") {
+		past, err = strconv.Atoi(r.URL.Query().Get("past"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert past: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	upcoming := daily.ScheduleDefaultDays
+	if r.URL.Query().Has("upcoming") {
+		upcoming, err = strconv.Atoi(r.URL.Query().Get("upcoming"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert upcoming: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	schedule, err := daily.Schedule(db.Store, past, upcoming)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, schedule)
+}
+
+func handleDailyEventRankings(w http.ResponseWriter, r *http.Request) {
+	event, err := strconv.ParseInt(r.URL.Query().Get("event"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert event: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rankings, err := daily.EventRankings(db.Store, event, page)
+	if err != nil {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, rankings)
+}
+
+func handleDailyEventRankingPageCount(w http.ResponseWriter, r *http.Request) {
+	event, err := strconv.ParseInt(r.URL.Query().Get("event"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert event: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	count, err := daily.EventRankingPageCount(db.Store, event)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
+		return
 	}
 
 	fmt.Fprint(w, count)
@@ -637,7 +924,7 @@ func handleProviderCallback(w http.ResponseWriter, r *http.Request) {
 	case "google":
 		externalAuthId, err = account.Google.HandleGoogleCallback(w, r)
 	default:
-		http.Error(w, "invalid provider", http.StatusBadRequest)
+		httpError(w, r, apierror.ErrInvalidParameter.Withf("invalid provider"), http.StatusBadRequest)
 		return
 	}
 
@@ -672,6 +959,10 @@ func handleProviderCallback(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if provider == "discord" {
+			syncDiscordRolesForUsername(r, userName, externalAuthId)
+		}
+
 	} else {
 		var userName string
 		switch provider {
@@ -685,6 +976,10 @@ func handleProviderCallback(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if provider == "discord" {
+			syncDiscordRolesForUsername(r, userName, externalAuthId)
+		}
+
 		sessionToken, err := account.GenerateTokenForUsername(db.Store, userName)
 		if err != nil {
 			http.Redirect(w, r, account.GameURL, http.StatusSeeOther)
@@ -705,6 +1000,18 @@ func handleProviderCallback(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, account.GameURL, http.StatusSeeOther)
 }
 
+// syncDiscordRolesForUsername refreshes the Discord-sourced roles of a user
+// that just authenticated through Discord. Failures are logged, not fatal.
+func syncDiscordRolesForUsername(r *http.Request, username, discordId string) {
+	uuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err == nil {
+		err = account.SyncDiscordRoles(db.Store, uuid, discordId)
+	}
+	if err != nil {
+		log.Printf("%s: failed to sync discord roles for %s: %s", r.URL.Path, username, err)
+	}
+}
+
 type HandleProviderLogoutStore interface {
 	RemoveDiscordIdByUUID(uuid []byte) error
 	RemoveGoogleIdByUUID(uuid []byte) error
@@ -723,7 +1030,7 @@ func handleProviderLogout(w http.ResponseWriter, r *http.Request) {
 	case "google":
 		err = db.Store.RemoveGoogleIdByUUID(uuid)
 	default:
-		http.Error(w, "invalid provider", http.StatusBadRequest)
+		httpError(w, r, apierror.ErrInvalidParameter.Withf("invalid provider"), http.StatusBadRequest)
 		return
 	}
 	if err != nil {
@@ -733,34 +1040,20 @@ func handleProviderLogout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func handleAdminDiscordLink(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuidFromRequest(r)
-	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
-		return
-	}
-
-	userDiscordId, err := db.Store.FetchDiscordIdByUUID(uuid)
-	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
-		return
-	}
-
-	hasRole, err := account.Discord.IsUserDiscordAdmin(userDiscordId, account.DiscordGuildID)
-	if !hasRole || err != nil {
-		httpError(w, r, fmt.Errorf("user does not have the required role"), http.StatusForbidden)
-		return
-	}
-
+func handleAdminDiscordLink(w http.ResponseWriter, r *http.Request, uuid []byte) {
 	username := r.PostFormValue("username")
 	discordId := r.PostFormValue("discordId")
 
 	// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
 	// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
-	_, err = db.Store.CheckUsernameExists(username)
+	_, err := db.Store.CheckUsernameExists(username)
 	if err != nil {
-		httpError(w, r, fmt.Errorf("username does not exist on the server"), http.StatusNotFound)
-		return
+		// the account may have been renamed since, so follow it to its current name
+		username, err = db.Store.FetchUsernameByPreviousUsername(username)
+		if err != nil {
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+			return
+		}
 	}
 
 	userUuid, err := db.Store.FetchUUIDFromUsername(username)
@@ -769,103 +1062,85 @@ func handleAdminDiscordLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.Store.AddDiscordIdByUUID(discordId, userUuid)
+	oldDiscordId, err := db.Store.FetchDiscordIdByUUID(userUuid)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("%s: %s added discord id %s to username %s", r.URL.Path, userDiscordId, discordId, username)
-
-	w.WriteHeader(http.StatusOK)
-}
-
-func handleAdminDiscordUnlink(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuidFromRequest(r)
+	err = db.Store.AddDiscordIdByUUID(discordId, userUuid)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	userDiscordId, err := db.Store.FetchDiscordIdByUUID(uuid)
-	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
-		return
-	}
+	auditAdminAction(r, uuid, defs.AuditActionDiscordLink, userUuid, oldDiscordId, discordId)
 
-	hasRole, err := account.Discord.IsUserDiscordAdmin(userDiscordId, account.DiscordGuildID)
-	if !hasRole || err != nil {
-		httpError(w, r, fmt.Errorf("user does not have the required role"), http.StatusForbidden)
-		return
-	}
+	w.WriteHeader(http.StatusOK)
+}
 
+func handleAdminDiscordUnlink(w http.ResponseWriter, r *http.Request, uuid []byte) {
 	username := r.PostFormValue("username")
 	discordId := r.PostFormValue("discordId")
 
+	var err error
 	switch {
 	case username != "":
-		log.Printf("Username given, removing discordId")
 		// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
 		// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
 		_, err = db.Store.CheckUsernameExists(username)
 		if err != nil {
-			httpError(w, r, fmt.Errorf("username does not exist on the server"), http.StatusNotFound)
-			return
-		}
-
-		userUuid, err := db.Store.FetchUUIDFromUsername(username)
-		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
-			return
-		}
-
-		err = db.Store.RemoveDiscordIdByUUID(userUuid)
-		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
 			return
 		}
 	case discordId != "":
-		log.Printf("DiscordID given, removing discordId")
-		err = db.Store.RemoveDiscordIdByDiscordId(discordId)
+		username, err = db.Store.FetchUsernameByDiscordId(discordId)
 		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, fmt.Errorf("discord id is not linked to any account"), http.StatusNotFound)
 			return
 		}
+	default:
+		httpError(w, r, fmt.Errorf("missing username or discordId"), http.StatusBadRequest)
+		return
 	}
 
-	log.Printf("%s: %s removed discord id %s from username %s", userDiscordId, r.URL.Path, r.Form.Get("discordId"), r.Form.Get("username"))
-
-	w.WriteHeader(http.StatusOK)
-}
-
-func handleAdminGoogleLink(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuidFromRequest(r)
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	userDiscordId, err := db.Store.FetchDiscordIdByUUID(uuid)
+	oldDiscordId, err := db.Store.FetchDiscordIdByUUID(userUuid)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	hasRole, err := account.Discord.IsUserDiscordAdmin(userDiscordId, account.DiscordGuildID)
-	if !hasRole || err != nil {
-		httpError(w, r, fmt.Errorf("user does not have the required role"), http.StatusForbidden)
+	err = db.Store.RemoveDiscordIdByUUID(userUuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
+	auditAdminAction(r, uuid, defs.AuditActionDiscordUnlink, userUuid, oldDiscordId, "")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminGoogleLink(w http.ResponseWriter, r *http.Request, uuid []byte) {
 	username := r.PostFormValue("username")
 	googleId := r.PostFormValue("googleId")
 
 	// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
 	// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
-	_, err = db.Store.CheckUsernameExists(username)
+	_, err := db.Store.CheckUsernameExists(username)
 	if err != nil {
-		httpError(w, r, fmt.Errorf("username does not exist on the server"), http.StatusNotFound)
-		return
+		// the account may have been renamed since, so follow it to its current name
+		username, err = db.Store.FetchUsernameByPreviousUsername(username)
+		if err != nil {
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+			return
+		}
 	}
 
 	userUuid, err := db.Store.FetchUUIDFromUsername(username)
@@ -874,118 +1149,749 @@ func handleAdminGoogleLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldGoogleId, err := db.Store.FetchGoogleIdByUUID(userUuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
 	err = db.Store.AddGoogleIdByUUID(googleId, userUuid)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("%s: %s added google id %s to username %s", r.URL.Path, userDiscordId, googleId, username)
+	auditAdminAction(r, uuid, defs.AuditActionGoogleLink, userUuid, oldGoogleId, googleId)
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func handleAdminGoogleUnlink(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuidFromRequest(r)
+func handleAdminGoogleUnlink(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.PostFormValue("username")
+	googleId := r.PostFormValue("googleId")
+
+	var err error
+	switch {
+	case username != "":
+		// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
+		// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
+		_, err = db.Store.CheckUsernameExists(username)
+		if err != nil {
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+			return
+		}
+	case googleId != "":
+		username, err = db.Store.FetchUsernameByGoogleId(googleId)
+		if err != nil {
+			httpError(w, r, fmt.Errorf("google id is not linked to any account"), http.StatusNotFound)
+			return
+		}
+	default:
+		httpError(w, r, fmt.Errorf("missing username or googleId"), http.StatusBadRequest)
+		return
+	}
+
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	userDiscordId, err := db.Store.FetchDiscordIdByUUID(uuid)
+	oldGoogleId, err := db.Store.FetchGoogleIdByUUID(userUuid)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	hasRole, err := account.Discord.IsUserDiscordAdmin(userDiscordId, account.DiscordGuildID)
-	if !hasRole || err != nil {
-		httpError(w, r, fmt.Errorf("user does not have the required role"), http.StatusForbidden)
+	err = db.Store.RemoveGoogleIdByUUID(userUuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionGoogleUnlink, userUuid, oldGoogleId, "")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminSearch(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.URL.Query().Get("username")
+
+	// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
+	// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
+	_, err := db.Store.CheckUsernameExists(username)
+	if err != nil {
+		// the account may have been renamed since, so follow it to its current name
+		username, err = db.Store.FetchUsernameByPreviousUsername(username)
+		if err != nil {
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+			return
+		}
+	}
+
+	// this does a single call that does a query for multiple columns from our database and makes an object out of it, which is returned to us
+	adminSearchResult, err := db.Store.FetchAdminDetailsByUsername(username)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err == nil {
+		systemData, err := savedata.GetSystem(db.Store, userUuid)
+		if err == nil {
+			adminSearchResult.SystemData = &systemData
+		}
+
+		usernameHistory, err := db.Store.FetchUsernameHistory(userUuid)
+		if err == nil {
+			adminSearchResult.UsernameHistory = usernameHistory
+		}
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionSearch, userUuid, "", r.URL.RawQuery)
+
+	writeJSON(w, r, adminSearchResult)
+}
+
+func handleAdminRoles(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	roles, err := db.Store.FetchRoles()
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, roles)
+}
+
+func handleAdminRoleGrant(w http.ResponseWriter, r *http.Request, uuid []byte) {
 	username := r.PostFormValue("username")
-	googleId := r.PostFormValue("googleId")
+	role := r.PostFormValue("role")
 
-	switch {
-	case username != "":
-		log.Printf("Username given, removing googleId")
-		// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
-		// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
-		_, err = db.Store.CheckUsernameExists(username)
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	err = account.GrantRole(db.Store, userUuid, role)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionRoleGrant, userUuid, "", role)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminRoleRevoke(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.PostFormValue("username")
+	role := r.PostFormValue("role")
+
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	err = db.Store.RevokeAccountRole(userUuid, role)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionRoleRevoke, userUuid, role, "")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminAuditLog(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	filter := defs.AuditLogFilter{
+		Action: defs.AuditAction(r.URL.Query().Get("action")),
+		From:   r.URL.Query().Get("from"),
+		To:     r.URL.Query().Get("to"),
+		Page:   1,
+	}
+
+	var err error
+	if r.URL.Query().Has("page") {
+		filter.Page, err = strconv.Atoi(r.URL.Query().Get("page"))
 		if err != nil {
-			httpError(w, r, fmt.Errorf("username does not exist on the server"), http.StatusNotFound)
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
 			return
 		}
+	}
 
-		userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if r.URL.Query().Has("pageSize") {
+		filter.PageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
 		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
 			return
 		}
+	}
 
-		err = db.Store.RemoveGoogleIdByUUID(userUuid)
+	if actor := r.URL.Query().Get("actor"); actor != "" {
+		filter.Actor, err = db.Store.FetchUUIDFromUsername(actor)
 		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, fmt.Errorf("actor does not exist on the server"), http.StatusNotFound)
 			return
 		}
-	case googleId != "":
-		log.Printf("DiscordID given, removing googleId")
-		err = db.Store.RemoveGoogleIdByDiscordId(googleId)
+	}
+
+	if target := r.URL.Query().Get("target"); target != "" {
+		filter.Target, err = db.Store.FetchUUIDFromUsername(target)
 		if err != nil {
-			httpError(w, r, err, http.StatusInternalServerError)
+			httpError(w, r, fmt.Errorf("target does not exist on the server"), http.StatusNotFound)
 			return
 		}
 	}
 
-	log.Printf("%s: %s removed google id %s from username %s", userDiscordId, r.URL.Path, r.Form.Get("googleId"), r.Form.Get("username"))
+	page, err := admin.AuditLog(db.Store, filter)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, page)
+}
+
+func handleAdminBan(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.PostFormValue("username")
+	reason := r.PostFormValue("reason")
+	evidence := r.PostFormValue("evidence")
+
+	var duration time.Duration
+	if r.PostFormValue("duration") != "" {
+		var err error
+		duration, err = time.ParseDuration(r.PostFormValue("duration"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to parse duration: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	err = admin.Ban(db.Store, userUuid, uuid, reason, evidence, duration)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	daily.InvalidateLeaderboards()
+
+	after := reason
+	if duration > 0 {
+		after += " (" + duration.String() + ")"
+	}
+	auditAdminAction(r, uuid, defs.AuditActionBan, userUuid, "", after)
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func handleAdminSearch(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuidFromRequest(r)
+func handleAdminUnban(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.PostFormValue("username")
+	reason := r.PostFormValue("reason")
+
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
 		return
 	}
 
-	userDiscordId, err := db.Store.FetchDiscordIdByUUID(uuid)
+	ban, err := admin.Unban(db.Store, userUuid, uuid, reason)
 	if err != nil {
-		httpError(w, r, err, http.StatusUnauthorized)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpError(w, r, fmt.Errorf("account is not banned"), http.StatusNotFound)
+			return
+		}
+
+		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	hasRole, err := account.Discord.IsUserDiscordAdmin(userDiscordId, account.DiscordGuildID)
-	if !hasRole || err != nil {
-		httpError(w, r, fmt.Errorf("user does not have the required role"), http.StatusForbidden)
+	daily.InvalidateLeaderboards()
+
+	auditAdminAction(r, uuid, defs.AuditActionUnban, userUuid, ban.Reason, reason)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminBans(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	var err error
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var activeOnly bool
+	if r.URL.Query().Has("active") {
+		activeOnly, err = strconv.ParseBool(r.URL.Query().Get("active"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert active: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var target []byte
+	if username := r.URL.Query().Get("username"); username != "" {
+		target, err = db.Store.FetchUUIDFromUsername(username)
+		if err != nil {
+			httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+			return
+		}
+	}
+
+	bans, err := admin.Bans(db.Store, target, activeOnly, page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
+	writeJSON(w, r, bans)
+}
+
+func handleAdminAccountSearch(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	query := r.URL.Query()
+
+	filter := defs.AccountSearchFilter{
+		Username:       query.Get("username"),
+		UsernameMatch:  defs.UsernameMatch(query.Get("match")),
+		DiscordId:      query.Get("discordId"),
+		GoogleId:       query.Get("googleId"),
+		RegisteredFrom: query.Get("registeredFrom"),
+		RegisteredTo:   query.Get("registeredTo"),
+		ActiveFrom:     query.Get("activeFrom"),
+		ActiveTo:       query.Get("activeTo"),
+		Page:           1,
+	}
+
+	var err error
+	if query.Has("page") {
+		filter.Page, err = strconv.Atoi(query.Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if query.Has("pageSize") {
+		filter.PageSize, err = strconv.Atoi(query.Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if query.Has("trainerId") {
+		trainerId, err := strconv.Atoi(query.Get("trainerId"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert trainerId: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		filter.TrainerId = &trainerId
+	}
+
+	if query.Has("secretId") {
+		secretId, err := strconv.Atoi(query.Get("secretId"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert secretId: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		filter.SecretId = &secretId
+	}
+
+	if query.Has("banned") {
+		banned, err := strconv.ParseBool(query.Get("banned"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert banned: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		filter.Banned = &banned
+	}
+
+	if query.Has("uuid") {
+		filter.Uuid, err = hex.DecodeString(query.Get("uuid"))
+		if err != nil || len(filter.Uuid) != account.UUIDSize {
+			httpError(w, r, fmt.Errorf("invalid uuid"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := admin.Search(db.Store, filter)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionSearch, nil, "", r.URL.RawQuery)
+
+	writeJSON(w, r, results)
+}
+
+func handleAdminAccountDelete(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	username := r.PostFormValue("username")
+
+	var immediate bool
+	if r.PostFormValue("immediate") != "" {
+		var err error
+		immediate, err = strconv.ParseBool(r.PostFormValue("immediate"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert immediate: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	after := "scheduled"
+	if immediate {
+		err = account.Purge(db.Store, userUuid)
+		daily.InvalidateLeaderboards()
+		after = "immediate"
+	} else {
+		_, err = account.ScheduleDeletion(db.Store, userUuid, account.DeletionGracePeriod)
+	}
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	// a purged account no longer resolves, so the username is kept as the old value
+	auditAdminAction(r, uuid, defs.AuditActionDelete, userUuid, username, after)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminAccountExport(w http.ResponseWriter, r *http.Request, uuid []byte) {
 	username := r.URL.Query().Get("username")
 
-	// this does a quick call to make sure the username exists on the server before allowing the rest of the code to run
-	// this calls error value 404 (StatusNotFound) if there's no data; this means the username does not exist in the server
-	_, err = db.Store.CheckUsernameExists(username)
+	userUuid, err := db.Store.FetchUUIDFromUsername(username)
 	if err != nil {
-		httpError(w, r, fmt.Errorf("username does not exist on the server"), http.StatusNotFound)
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
 		return
 	}
 
-	// this does a single call that does a query for multiple columns from our database and makes an object out of it, which is returned to us
-	adminSearchResult, err := db.Store.FetchAdminDetailsByUsername(username)
+	auditAdminAction(r, uuid, defs.AuditActionExport, userUuid, "", "")
+
+	writeAccountExport(w, r, userUuid)
+}
+
+func handleAdminDailyOverride(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	date := r.PostFormValue("date")
+
+	seed, err := daily.Override(db.Store, date, r.PostFormValue("seed"), r.PostFormValue("name"), uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionDailyOverride, nil, "", date+" "+seed)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminDailyRestore(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	date := r.PostFormValue("date")
+
+	seed, err := daily.RemoveOverride(db.Store, date)
 	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionDailyRestore, nil, date+" "+seed, "")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminDailyEvent(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	starts, err := time.Parse(time.RFC3339, r.PostFormValue("starts"))
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to parse starts: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ends, err := time.Parse(time.RFC3339, r.PostFormValue("ends"))
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to parse ends: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	event, err := daily.AddEvent(db.Store, r.PostFormValue("name"), r.PostFormValue("seed"), starts, ends, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionEventCreate, nil, "", fmt.Sprintf("%d %s (%s - %s)", event.Id, event.Name, event.Starts, event.Ends))
+
+	writeJSON(w, r, event)
+}
+
+func handleAdminDailyEventRemove(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	id, err := strconv.ParseInt(r.PostFormValue("event"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert event: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	event, err := daily.RemoveEvent(db.Store, id)
+	if err != nil {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionEventRemove, nil, fmt.Sprintf("%d %s (%s - %s)", event.Id, event.Name, event.Starts, event.Ends), "")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminDailyRotateSecret(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	secret, err := daily.RotateSecret(db.Store)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionRotateSecret, nil, "", fmt.Sprintf("version %d from %s", secret.Version, secret.ActiveFrom))
+
+	writeJSON(w, r, secret)
+}
+
+func handleAdminFlaggedRuns(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	var err error
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	status := defs.RunFlagPending
+	if r.URL.Query().Has("status") {
+		status = defs.RunFlagStatus(r.URL.Query().Get("status"))
+	}
+
+	flags, err := admin.Flags(db.Store, status, page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, flags)
+}
+
+func handleAdminFlaggedRun(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	flag, err := db.Store.FetchRunFlagById(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpError(w, r, fmt.Errorf("flagged run does not exist"), http.StatusNotFound)
+			return
+		}
+
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
-	uuid, err = db.Store.FetchUUIDFromUsername(username)
-	if err == nil {
-		systemData, err := savedata.GetSystem(db.Store, uuid)
-		if err == nil {
-			adminSearchResult.SystemData = &systemData
+
+	writeJSON(w, r, flag)
+}
+
+func handleAdminRunApprove(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	flag, ok := reviewFlaggedRun(w, r, uuid, true)
+	if !ok {
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionRunApprove, flag.Uuid, strings.Join(flag.Reasons, "; "), fmt.Sprintf("%s %d (wave %d)", flag.Seed, flag.Score, flag.Wave))
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleAdminRunReject(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	var ban bool
+	if r.PostFormValue("ban") != "" {
+		var err error
+		ban, err = strconv.ParseBool(r.PostFormValue("ban"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert ban: %s", err), http.StatusBadRequest)
+			return
 		}
 	}
 
-	writeJSON(w, r, adminSearchResult)
-	log.Printf("%s: %s searched for username %s", userDiscordId, r.URL.Path, username)
+	var duration time.Duration
+	if r.PostFormValue("duration") != "" {
+		var err error
+		duration, err = time.ParseDuration(r.PostFormValue("duration"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to parse duration: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	reason := r.PostFormValue("reason")
+	if ban && reason == "" {
+		httpError(w, r, fmt.Errorf("missing ban reason"), http.StatusBadRequest)
+		return
+	}
+
+	flag, ok := reviewFlaggedRun(w, r, uuid, false)
+	if !ok {
+		return
+	}
+
+	auditAdminAction(r, uuid, defs.AuditActionRunReject, flag.Uuid, strings.Join(flag.Reasons, "; "), fmt.Sprintf("%s %d (wave %d)", flag.Seed, flag.Score, flag.Wave))
+
+	if ban {
+		err := admin.Ban(db.Store, flag.Uuid, uuid, reason, r.PostFormValue("evidence"), duration)
+		if err != nil {
+			httpError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		daily.InvalidateLeaderboards()
+
+		after := reason
+		if duration > 0 {
+			after += " (" + duration.String() + ")"
+		}
+		auditAdminAction(r, uuid, defs.AuditActionBan, flag.Uuid, "", after)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// reviewFlaggedRun settles the flag given in the request, writing the error
+// response if that fails.
+func reviewFlaggedRun(w http.ResponseWriter, r *http.Request, uuid []byte, approve bool) (defs.RunFlag, bool) {
+	id, err := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		httpError(w, r, fmt.Errorf("failed to convert id: %s", err), http.StatusBadRequest)
+		return defs.RunFlag{}, false
+	}
+
+	flag, err := admin.ReviewFlag(db.Store, id, approve, uuid)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			httpError(w, r, fmt.Errorf("flagged run does not exist"), http.StatusNotFound)
+		case errors.Is(err, apierror.ErrRunFlagReviewed):
+			httpError(w, r, err, http.StatusConflict)
+		default:
+			httpError(w, r, err, http.StatusInternalServerError)
+		}
+
+		return flag, false
+	}
+
+	daily.InvalidateLeaderboards()
+
+	return flag, true
+}
+
+func handleAdminRunLedger(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	userUuid, err := db.Store.FetchUUIDFromUsername(r.URL.Query().Get("username"))
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	ledger, err := admin.Ledger(db.Store, userUuid, r.URL.Query().Get("seed"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, ledger)
+}
+
+func handleAdminMetrics(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	writeJSON(w, r, map[string]any{"bodyRejections": bodyRejectionCounts()})
+}
+
+func handleAdminSystemLedger(w http.ResponseWriter, r *http.Request, uuid []byte) {
+	userUuid, err := db.Store.FetchUUIDFromUsername(r.URL.Query().Get("username"))
+	if err != nil {
+		httpError(w, r, apierror.ErrUserNotFound, http.StatusNotFound)
+		return
+	}
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert page: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pageSize int
+	if r.URL.Query().Has("pageSize") {
+		pageSize, err = strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert pageSize: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var flagged bool
+	if r.URL.Query().Has("flagged") {
+		flagged, err = strconv.ParseBool(r.URL.Query().Get("flagged"))
+		if err != nil {
+			httpError(w, r, fmt.Errorf("failed to convert flagged: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ledger, err := admin.SystemLedger(db.Store, userUuid, flagged, page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, ledger)
 }
@@ -0,0 +1,203 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package api
+
+import (
+	"log"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/account"
+	"github.com/pagefaultgames/rogueserver/api/daily"
+	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/api/stats"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// scheduleDiscordRoleSync periodically re-syncs every Discord-sourced role
+// grant, so that roles removed in the guild are also removed here.
+func scheduleDiscordRoleSync[T discordRoleSyncStore](store T) error {
+	if len(account.DiscordRoleMap) == 0 || account.DiscordGuildID == "" {
+		return nil
+	}
+
+	_, err := scheduler.AddFunc("@every 1h", func() {
+		holders, err := store.FetchDiscordRoleHolders()
+		if err != nil {
+			log.Printf("failed to fetch discord role holders: %s", err)
+			return
+		}
+
+		for uuid, discordId := range holders {
+			err = account.SyncDiscordRoles(store, []byte(uuid), discordId)
+			if err != nil {
+				log.Printf("failed to sync discord roles for %s: %s", discordId, err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type discordRoleSyncStore interface {
+	FetchDiscordRoleHolders() (map[string]string, error)
+	ReplaceAccountRolesFromSource(uuid []byte, source defs.RoleSource, roles []string) error
+}
+
+// scheduleBanExpiry periodically lifts bans whose expiry has passed.
+func scheduleBanExpiry[T banExpiryStore](store T) error {
+	_, err := scheduler.AddFunc("@every 5m", func() {
+		unbanned, err := store.LiftExpiredBans()
+		if err != nil {
+			log.Printf("failed to lift expired bans: %s", err)
+			return
+		}
+
+		if len(unbanned) > 0 {
+			daily.InvalidateLeaderboards()
+			log.Printf("lifted expired bans on %d accounts", len(unbanned))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type banExpiryStore interface {
+	LiftExpiredBans() ([][]byte, error)
+}
+
+// scheduleAccountPurge periodically purges accounts whose deletion grace
+// period has passed.
+func scheduleAccountPurge[T accountPurgeStore](store T) error {
+	_, err := scheduler.AddFunc("@every 1h", func() {
+		uuids, err := store.FetchDueAccountDeletions()
+		if err != nil {
+			log.Printf("failed to fetch due account deletions: %s", err)
+			return
+		}
+
+		for _, uuid := range uuids {
+			err = account.Purge(store, uuid)
+			if err != nil {
+				log.Printf("failed to purge account %x: %s", uuid, err)
+			}
+		}
+
+		if len(uuids) > 0 {
+			daily.InvalidateLeaderboards()
+			log.Printf("purged %d accounts", len(uuids))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type accountPurgeStore interface {
+	FetchDueAccountDeletions() ([][]byte, error)
+	account.PurgeStore
+}
+
+// LedgerRetention is how long session and system ledger entries are kept,
+// unless they have been flagged.
+var LedgerRetention = 30 * 24 * time.Hour
+
+// scheduleLedgerPrune periodically deletes session and system ledger entries
+// past their retention.
+func scheduleLedgerPrune[T ledgerPruneStore](store T) error {
+	_, err := scheduler.AddFunc("@every 1h", func() {
+		cutoff := time.Now().UTC().Add(-LedgerRetention)
+
+		pruned, err := store.PruneSessionLedger(cutoff)
+		if err != nil {
+			log.Printf("failed to prune session ledger: %s", err)
+		} else if pruned > 0 {
+			log.Printf("pruned %d session ledger entries", pruned)
+		}
+
+		pruned, err = store.PruneSystemLedger(cutoff)
+		if err != nil {
+			log.Printf("failed to prune system ledger: %s", err)
+		} else if pruned > 0 {
+			log.Printf("pruned %d system ledger entries", pruned)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type ledgerPruneStore interface {
+	PruneSessionLedger(cutoff time.Time) (int64, error)
+	PruneSystemLedger(cutoff time.Time) (int64, error)
+}
+
+// scheduleHistoryPrune periodically deletes archived runs past their
+// retention.
+func scheduleHistoryPrune[T historyPruneStore](store T) error {
+	_, err := scheduler.AddFunc("@every 1h", func() {
+		pruned, err := store.PruneSessionHistory(time.Now().UTC().Add(-savedata.HistoryRetention))
+		if err != nil {
+			log.Printf("failed to prune session history: %s", err)
+			return
+		}
+
+		if pruned > 0 {
+			log.Printf("pruned %d archived runs", pruned)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type historyPruneStore interface {
+	PruneSessionHistory(cutoff time.Time) (int64, error)
+}
+
+// scheduleDexStats aggregates the dex stats once a day, at a quiet hour since
+// it reads the system save of every active player.
+func scheduleDexStats[T stats.AggregateDexStore](store T) error {
+	_, err := scheduler.AddFunc("0 4 * * *", func() {
+		start := time.Now()
+
+		err := stats.AggregateDex(store)
+		if err != nil {
+			log.Printf("failed to aggregate dex stats: %s", err)
+			return
+		}
+
+		log.Printf("aggregated dex stats in %s", time.Since(start))
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -18,9 +18,11 @@
 package savedata
 
 import (
-	"fmt"
+	"database/sql"
+	"errors"
 	"log"
 
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
@@ -35,12 +37,18 @@ type ClearStore interface {
 	UpdateAccountLastActivity(uuid []byte) error
 	TryAddSeedCompletion(uuid []byte, seed string, mode int) (bool, error)
 	DeleteSessionSaveData(uuid []byte, slot int) error
-	AddOrUpdateAccountDailyRun(uuid []byte, score int, waveCompleted int) error
-	SetAccountBanned(uuid []byte, banned bool) error
+	AddOrUpdateAccountDailyRun(uuid []byte, date string, score int, waveCompleted int) error
+	FetchActiveEventRunBySeed(seed string) (int64, error)
+	AddOrUpdateAccountEventRun(uuid []byte, event int64, score int, waveCompleted int) error
+	QuarantineRunFlag(uuid []byte, seed, date string, event int64, score, wave int, session defs.SessionSaveData) error
+	ValidateRunStore
+	ArchiveStore
 }
 
-// /savedata/clear - mark session save data as cleared and delete
-func Clear[T ClearStore](store T, uuid []byte, slot int, seed string, save defs.SessionSaveData) (ClearResponse, error) {
+// /savedata/clear - mark session save data as cleared, archive it and delete.
+// date and seed identify the current daily run. Daily and event runs that
+// fail the run checks are quarantined for review instead of being recorded.
+func Clear[T ClearStore](store T, uuid []byte, slot int, date, seed string, save defs.SessionSaveData) (ClearResponse, error) {
 	var response ClearResponse
 	err := store.UpdateAccountLastActivity(uuid)
 	if err != nil {
@@ -48,24 +56,55 @@ func Clear[T ClearStore](store T, uuid []byte, slot int, seed string, save defs.
 	}
 
 	if slot < 0 || slot >= defs.SessionSlotCount {
-		return response, fmt.Errorf("slot id %d out of range", slot)
+		return response, apierror.ErrInvalidSlot.Withf("slot id %d out of range", slot)
 	}
 
 	sessionCompleted := validateSessionCompleted(save)
 
-	if save.GameMode == 3 && save.Seed == seed {
+	// a run that could not be checked is not recorded as clean; the session
+	// is kept so the clear can be retried
+	flagged, err := ValidateRun(store, uuid, save, true)
+	if err != nil {
+		return response, apierror.Internalf("failed to validate run: %s", err)
+	}
+
+	if save.GameMode == 3 {
 		waveCompleted := save.WaveIndex
 		if !sessionCompleted {
 			waveCompleted--
 		}
 
-		if save.Score >= 20000 {
-			store.SetAccountBanned(uuid, true)
+		var event int64
+		if save.Seed != seed {
+			// event runs are played in daily mode with the event's seed
+			event, err = store.FetchActiveEventRunBySeed(save.Seed)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("failed to fetch event run: %s", err)
+			}
 		}
 
-		err = store.AddOrUpdateAccountDailyRun(uuid, save.Score, waveCompleted)
-		if err != nil {
-			log.Printf("failed to add or update daily run record: %s", err)
+		if save.Seed == seed || event != 0 {
+			if flagged {
+				runDate := date
+				if event != 0 {
+					runDate = ""
+				}
+
+				err = store.QuarantineRunFlag(uuid, save.Seed, runDate, event, save.Score, waveCompleted, save)
+				if err != nil {
+					log.Printf("failed to quarantine flagged run: %s", err)
+				}
+			} else if event == 0 {
+				err = store.AddOrUpdateAccountDailyRun(uuid, date, save.Score, waveCompleted)
+				if err != nil {
+					log.Printf("failed to add or update daily run record: %s", err)
+				}
+			} else {
+				err = store.AddOrUpdateAccountEventRun(uuid, event, save.Score, waveCompleted)
+				if err != nil {
+					log.Printf("failed to add or update event run record: %s", err)
+				}
+			}
 		}
 	}
 
@@ -76,6 +115,16 @@ func Clear[T ClearStore](store T, uuid []byte, slot int, seed string, save defs.
 		}
 	}
 
+	result := defs.SessionHistoryResultLoss
+	if sessionCompleted {
+		result = defs.SessionHistoryResultWin
+	}
+
+	err = archiveSession(store, uuid, save, result)
+	if err != nil {
+		log.Printf("failed to archive session: %s", err)
+	}
+
 	err = store.DeleteSessionSaveData(uuid, slot)
 	if err != nil {
 		log.Printf("failed to delete session save data: %s", err)
@@ -18,9 +18,9 @@
 package savedata
 
 import (
-	"fmt"
 	"log"
 
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
@@ -41,13 +41,13 @@ func Delete[T DeleteStore](store T, uuid []byte, datatype, slot int) error {
 	switch datatype {
 	case 1: // Session
 		if slot < 0 || slot >= defs.SessionSlotCount {
-			err = fmt.Errorf("slot id %d out of range", slot)
+			err = apierror.ErrInvalidSlot.Withf("slot id %d out of range", slot)
 			break
 		}
 
 		err = store.DeleteSessionSaveData(uuid, slot)
 	default:
-		err = fmt.Errorf("invalid data type")
+		err = apierror.ErrInvalidDataType
 	}
 	if err != nil {
 		return err
@@ -0,0 +1,95 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	HistoryDefaultPageSize = 10
+	HistoryMaxPageSize     = 50
+)
+
+var (
+	// HistoryMaxRuns is how many archived runs are kept per account.
+	HistoryMaxRuns = 100
+
+	// HistoryRetention is how long archived runs are kept.
+	HistoryRetention = 90 * 24 * time.Hour
+)
+
+// Interface for database operations needed for archiving runs.
+type ArchiveStore interface {
+	AddSessionHistory(uuid []byte, history defs.SessionHistoryData, maxRuns int) error
+}
+
+// archiveSession stores a finished session in the account's run history.
+func archiveSession[T ArchiveStore](store T, uuid []byte, save defs.SessionSaveData, result defs.SessionHistoryResult) error {
+	history := defs.SessionHistoryData{
+		Seed:        save.Seed,
+		PlayTime:    save.PlayTime,
+		Result:      result,
+		GameMode:    save.GameMode,
+		Party:       save.Party,
+		Modifiers:   save.Modifiers,
+		Money:       save.Money,
+		Score:       save.Score,
+		WaveIndex:   save.WaveIndex,
+		BattleType:  save.BattleType,
+		GameVersion: save.GameVersion,
+		Timestamp:   save.Timestamp,
+	}
+
+	return store.AddSessionHistory(uuid, history, HistoryMaxRuns)
+}
+
+// Interface for database operations needed for browsing run history.
+type HistoryStore interface {
+	FetchSessionHistory(uuid []byte, page, pageSize int) (defs.SessionHistoryPage, error)
+}
+
+// /savedata/history - list the account's archived runs, newest first
+func History[T HistoryStore](store T, uuid []byte, page, pageSize int) (defs.SessionHistoryPage, error) {
+	if page < 1 {
+		return defs.SessionHistoryPage{}, apierror.ErrInvalidPage
+	}
+
+	if pageSize == 0 {
+		pageSize = HistoryDefaultPageSize
+	}
+
+	if pageSize < 1 || pageSize > HistoryMaxPageSize {
+		return defs.SessionHistoryPage{}, apierror.ErrInvalidPageSize.Withf("page size must be between 1 and %d", HistoryMaxPageSize)
+	}
+
+	return store.FetchSessionHistory(uuid, page, pageSize)
+}
+
+// Interface for database operations needed for fetching an archived run.
+type HistoryRunStore interface {
+	FetchSessionHistoryRun(uuid []byte, id int64) (defs.SessionHistoryData, error)
+}
+
+// /savedata/history/{id} - fetch one of the account's archived runs
+func HistoryRun[T HistoryRunStore](store T, uuid []byte, id int64) (defs.SessionHistoryData, error) {
+	return store.FetchSessionHistoryRun(uuid, id)
+}
@@ -0,0 +1,88 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// newLedgerEntry picks the fields kept in a run's ledger out of a session
+// save. Party members that can't be read are left out; checkParty reports
+// them.
+func newLedgerEntry(save defs.SessionSaveData) defs.SessionLedgerEntry {
+	party, _ := ledgerParty(save.Party)
+
+	return defs.SessionLedgerEntry{
+		Wave:         save.WaveIndex,
+		Money:        save.Money,
+		Score:        save.Score,
+		VictoryCount: save.VictoryCount,
+		PlayTime:     save.PlayTime,
+		Party:        party,
+		Modifiers:    ledgerModifiers(save.Modifiers),
+	}
+}
+
+// ledgerParty returns the species and level of each party member. It reports
+// false if any of them is malformed.
+func ledgerParty(party []defs.PokemonData) ([]defs.LedgerPokemon, bool) {
+	pokemon := make([]defs.LedgerPokemon, 0, len(party))
+
+	ok := true
+	for _, member := range party {
+		data, isMap := member.(map[string]interface{})
+		if !isMap {
+			ok = false
+			continue
+		}
+
+		species, hasSpecies := data["species"].(float64)
+		level, hasLevel := data["level"].(float64)
+		if !hasSpecies || !hasLevel {
+			ok = false
+			continue
+		}
+
+		pokemon = append(pokemon, defs.LedgerPokemon{Species: int(species), Level: int(level)})
+	}
+
+	return pokemon, ok
+}
+
+// ledgerModifiers returns the total stack count of a set of modifiers.
+// Modifiers without a stack count count once.
+func ledgerModifiers(modifiers []defs.PersistentModifierData) int {
+	var count int
+	for _, modifier := range modifiers {
+		data, ok := modifier.(map[string]interface{})
+		if !ok {
+			count++
+			continue
+		}
+
+		stacks, ok := data["stackCount"].(float64)
+		if !ok {
+			count++
+			continue
+		}
+
+		count += int(stacks)
+	}
+
+	return count
+}
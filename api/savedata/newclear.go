@@ -18,8 +18,7 @@
 package savedata
 
 import (
-	"fmt"
-
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
@@ -31,7 +30,7 @@ type NewClearStore interface {
 // /savedata/newclear - return whether a session is a new clear for its seed
 func NewClear[T NewClearStore](store T, uuid []byte, slot int) (bool, error) {
 	if slot < 0 || slot >= defs.SessionSlotCount {
-		return false, fmt.Errorf("slot id %d out of range", slot)
+		return false, apierror.ErrInvalidSlot.Withf("slot id %d out of range", slot)
 	}
 
 	session, err := store.ReadSessionSaveData(uuid, slot)
@@ -41,7 +40,7 @@ func NewClear[T NewClearStore](store T, uuid []byte, slot int) (bool, error) {
 
 	completed, err := store.ReadSeedCompleted(uuid, session.Seed)
 	if err != nil {
-		return false, fmt.Errorf("failed to read seed completed: %s", err)
+		return false, apierror.Internalf("failed to read seed completed: %s", err)
 	}
 
 	return !completed, nil
@@ -0,0 +1,427 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// A SchemaError reports where in a save a value doesn't have the expected
+// shape. Path uses the save's json field names, such as party[0].level.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+type schemaKind int
+
+const (
+	kindAny schemaKind = iota
+	kindObject
+	kindArray
+	kindNumber
+	kindInteger
+	kindString
+	kindBool
+	kindAttr // a dex attribute: a non-negative integer, sent as a string once it outgrows a float
+)
+
+var kindNames = map[schemaKind]string{
+	kindObject:  "object",
+	kindArray:   "array",
+	kindNumber:  "number",
+	kindInteger: "integer",
+	kindString:  "string",
+	kindBool:    "boolean",
+	kindAttr:    "integer or integer string",
+}
+
+// A schema describes the expected shape of a json value decoded into an
+// interface{} field.
+type schema struct {
+	Kind     schemaKind
+	Nullable bool
+
+	// object
+	Fields   map[string]*schema // shape of known fields, others are allowed
+	Required []string
+	Values   *schema // shape of every field, for objects used as maps
+
+	// array
+	Items    *schema
+	MaxItems int
+
+	// number and integer
+	Min, Max *float64
+}
+
+func bound(value float64) *float64 {
+	return &value
+}
+
+// validate checks value against the schema, returning the first mismatch.
+func (s *schema) validate(path string, value any) error {
+	if value == nil {
+		if s.Nullable || s.Kind == kindAny {
+			return nil
+		}
+
+		return &SchemaError{path, "expected " + kindNames[s.Kind] + ", got null"}
+	}
+
+	switch s.Kind {
+	case kindAny:
+		return nil
+	case kindObject:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return s.mismatch(path, value)
+		}
+
+		for _, field := range s.Required {
+			if _, ok := object[field]; !ok {
+				return &SchemaError{join(path, field), "missing required field"}
+			}
+		}
+
+		// walk fields in order so the reported error is stable
+		fields := make([]string, 0, len(object))
+		for field := range object {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+
+		for _, field := range fields {
+			fieldSchema := s.Values
+			if known, ok := s.Fields[field]; ok {
+				fieldSchema = known
+			}
+
+			if fieldSchema == nil {
+				continue
+			}
+
+			if err := fieldSchema.validate(join(path, field), object[field]); err != nil {
+				return err
+			}
+		}
+	case kindArray:
+		array, ok := value.([]any)
+		if !ok {
+			return s.mismatch(path, value)
+		}
+
+		if s.MaxItems > 0 && len(array) > s.MaxItems {
+			return &SchemaError{path, fmt.Sprintf("expected at most %d items, got %d", s.MaxItems, len(array))}
+		}
+
+		if s.Items != nil {
+			for i, item := range array {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case kindNumber, kindInteger:
+		number, ok := value.(float64)
+		if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+			return s.mismatch(path, value)
+		}
+
+		if s.Kind == kindInteger && number != math.Trunc(number) {
+			return &SchemaError{path, fmt.Sprintf("expected integer, got %v", number)}
+		}
+
+		if s.Min != nil && number < *s.Min {
+			return &SchemaError{path, fmt.Sprintf("expected at least %v, got %v", *s.Min, number)}
+		}
+
+		if s.Max != nil && number > *s.Max {
+			return &SchemaError{path, fmt.Sprintf("expected at most %v, got %v", *s.Max, number)}
+		}
+	case kindString:
+		if _, ok := value.(string); !ok {
+			return s.mismatch(path, value)
+		}
+	case kindBool:
+		if _, ok := value.(bool); !ok {
+			return s.mismatch(path, value)
+		}
+	case kindAttr:
+		switch value := value.(type) {
+		case float64:
+			if value < 0 || value != math.Trunc(value) {
+				return &SchemaError{path, fmt.Sprintf("expected non-negative integer, got %v", value)}
+			}
+		case string:
+			attr, ok := new(big.Int).SetString(value, 10)
+			if !ok || attr.Sign() < 0 {
+				return &SchemaError{path, fmt.Sprintf("expected non-negative integer string, got %q", value)}
+			}
+		default:
+			return s.mismatch(path, value)
+		}
+	}
+
+	return nil
+}
+
+func (s *schema) mismatch(path string, value any) error {
+	return &SchemaError{path, fmt.Sprintf("expected %s, got %s", kindNames[s.Kind], jsonType(value))}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+// saveSchemas holds the schemas of the interface{} fields of system and
+// session saves, keyed by json field name.
+type saveSchemas struct {
+	Since   string // first game version the schemas apply to
+	System  map[string]*schema
+	Session map[string]*schema
+}
+
+var (
+	pokemonSchema = &schema{
+		Kind:     kindObject,
+		Required: []string{"species", "level"},
+		Fields: map[string]*schema{
+			"species":      {Kind: kindInteger, Min: bound(1)},
+			"formIndex":    {Kind: kindInteger, Min: bound(0)},
+			"level":        {Kind: kindInteger, Min: bound(1), Max: bound(10000)},
+			"exp":          {Kind: kindNumber, Min: bound(0)},
+			"hp":           {Kind: kindNumber, Min: bound(0)},
+			"abilityIndex": {Kind: kindInteger, Min: bound(0)},
+			"nature":       {Kind: kindInteger, Min: bound(0)},
+			"shiny":        {Kind: kindBool},
+			"variant":      {Kind: kindInteger, Min: bound(0), Max: bound(2)},
+			"friendship":   {Kind: kindNumber},
+			"ivs":          {Kind: kindArray, Nullable: true, MaxItems: 6, Items: &schema{Kind: kindInteger, Min: bound(0), Max: bound(31)}},
+			"moveset":      {Kind: kindArray, Nullable: true, Items: &schema{Kind: kindObject, Nullable: true}},
+		},
+	}
+
+	modifierSchema = &schema{
+		Kind:     kindObject,
+		Required: []string{"className", "typeId", "stackCount"},
+		Fields: map[string]*schema{
+			"className":  {Kind: kindString},
+			"typeId":     {Kind: kindString},
+			"stackCount": {Kind: kindInteger, Min: bound(0)},
+			"player":     {Kind: kindBool},
+			"args":       {Kind: kindArray, Nullable: true},
+		},
+	}
+)
+
+// saveSchemaVersions lists the schemas for each game version that changed the
+// shape of a save, oldest first. A save is validated against the last entry
+// whose Since is not newer than its gameVersion.
+var saveSchemaVersions = []saveSchemas{
+	{
+		Since: "0.0.0",
+		System: map[string]*schema{
+			"gameStats": {
+				Kind:     kindObject,
+				Required: []string{"playTime"},
+				Fields:   map[string]*schema{"playTime": {Kind: kindNumber, Min: bound(0)}},
+				Values:   &schema{Kind: kindNumber},
+			},
+			"seenAttr":   {Kind: kindAttr},
+			"caughtAttr": {Kind: kindAttr},
+		},
+		Session: map[string]*schema{
+			"party":          pokemonSchema,
+			"enemyParty":     pokemonSchema,
+			"modifiers":      modifierSchema,
+			"enemyModifiers": modifierSchema,
+			"arena": {
+				Kind:     kindObject,
+				Required: []string{"biome"},
+				Fields: map[string]*schema{
+					"biome":   {Kind: kindInteger, Min: bound(0)},
+					"weather": {Kind: kindObject, Nullable: true},
+					"terrain": {Kind: kindObject, Nullable: true},
+					"tags":    {Kind: kindArray, Nullable: true},
+				},
+			},
+			"trainer": {
+				Kind:     kindObject,
+				Nullable: true,
+				Fields: map[string]*schema{
+					"trainerType": {Kind: kindInteger, Min: bound(0)},
+					"variant":     {Kind: kindInteger, Min: bound(0)},
+				},
+			},
+		},
+	},
+}
+
+// schemasFor returns the schemas that apply to a save of the given game
+// version. Saves without a version get the oldest schemas.
+func schemasFor(version string) (saveSchemas, error) {
+	schemas := saveSchemaVersions[0]
+	if version == "" {
+		return schemas, nil
+	}
+
+	for _, candidate := range saveSchemaVersions[1:] {
+		cmp, err := CompareGameVersion(candidate.Since, version)
+		if err != nil {
+			return schemas, &SchemaError{"gameVersion", err.Error()}
+		}
+
+		if cmp > 0 {
+			break
+		}
+
+		schemas = candidate
+	}
+
+	// check the version even when there is only one set of schemas
+	if _, err := parseGameVersion(version); err != nil {
+		return schemas, &SchemaError{"gameVersion", err.Error()}
+	}
+
+	return schemas, nil
+}
+
+// ValidateSystemSave checks the shape of the interface{} fields of a system
+// save, which json decoding accepts as anything.
+func ValidateSystemSave(save defs.SystemSaveData) error {
+	schemas, err := schemasFor(save.GameVersion)
+	if err != nil {
+		return err
+	}
+
+	if err := schemas.System["gameStats"].validate("gameStats", save.GameStats); err != nil {
+		return err
+	}
+
+	for _, species := range sortedKeys(save.DexData) {
+		entry := save.DexData[species]
+		path := "dexData." + strconv.Itoa(species)
+
+		if err := schemas.System["seenAttr"].validate(path+".seenAttr", entry.SeenAttr); err != nil {
+			return err
+		}
+
+		if err := schemas.System["caughtAttr"].validate(path+".caughtAttr", entry.CaughtAttr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSessionSave checks the shape of the interface{} fields of a
+// session save, which json decoding accepts as anything.
+func ValidateSessionSave(save defs.SessionSaveData) error {
+	schemas, err := schemasFor(save.GameVersion)
+	if err != nil {
+		return err
+	}
+
+	lists := []struct {
+		field string
+		items []any
+	}{
+		{"party", items(save.Party)},
+		{"enemyParty", items(save.EnemyParty)},
+		{"modifiers", items(save.Modifiers)},
+		{"enemyModifiers", items(save.EnemyModifiers)},
+	}
+
+	for _, list := range lists {
+		for i, item := range list.items {
+			if err := schemas.Session[list.field].validate(fmt.Sprintf("%s[%d]", list.field, i), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := schemas.Session["arena"].validate("arena", save.Arena); err != nil {
+		return err
+	}
+
+	return schemas.Session["trainer"].validate("trainer", save.Trainer)
+}
+
+func items[T any](list []T) []any {
+	converted := make([]any, len(list))
+	for i, item := range list {
+		converted[i] = item
+	}
+
+	return converted
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// PlayTime returns the play time recorded in a system save's game stats.
+func PlayTime(save defs.SystemSaveData) (float64, bool) {
+	stats, ok := save.GameStats.(map[string]any)
+	if !ok {
+		return 0, false
+	}
+
+	playTime, ok := stats["playTime"].(float64)
+
+	return playTime, ok
+}
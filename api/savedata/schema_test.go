@@ -0,0 +1,163 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func TestValidateSessionSave(t *testing.T) {
+	tests := []struct {
+		name string
+		save string
+		path string
+	}{
+		{
+			name: "valid",
+			save: `{"gameVersion": "1.12.0", "party": [{"species": 25, "level": 12, "ivs": [31, 0, 15, 20, 3, 9], "moveset": [{"moveId": 84}, null]}], "modifiers": [{"className": "ExpBoosterModifier", "typeId": "LUCKY_EGG", "stackCount": 1, "args": null}], "arena": {"biome": 1, "weather": null}, "trainer": null}`,
+		},
+		{
+			name: "missing level",
+			save: `{"party": [{"species": 25}], "arena": {"biome": 1}}`,
+			path: "party[0].level",
+		},
+		{
+			name: "fractional level",
+			save: `{"party": [{"species": 25, "level": 1.5}], "arena": {"biome": 1}}`,
+			path: "party[0].level",
+		},
+		{
+			name: "party member not an object",
+			save: `{"party": [{"species": 25, "level": 5}, "pikachu"], "arena": {"biome": 1}}`,
+			path: "party[1]",
+		},
+		{
+			name: "iv out of range",
+			save: `{"party": [{"species": 25, "level": 5, "ivs": [31, 32]}], "arena": {"biome": 1}}`,
+			path: "party[0].ivs[1]",
+		},
+		{
+			name: "modifier stack count",
+			save: `{"party": [{"species": 25, "level": 5}], "enemyModifiers": [{"className": "a", "typeId": "b", "stackCount": "1"}], "arena": {"biome": 1}}`,
+			path: "enemyModifiers[0].stackCount",
+		},
+		{
+			name: "missing arena",
+			save: `{"party": [{"species": 25, "level": 5}]}`,
+			path: "arena",
+		},
+		{
+			name: "bad version",
+			save: `{"gameVersion": "latest", "party": [], "arena": {"biome": 1}}`,
+			path: "gameVersion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var save defs.SessionSaveData
+			if err := json.Unmarshal([]byte(tt.save), &save); err != nil {
+				t.Fatalf("failed to decode save: %s", err)
+			}
+
+			err := ValidateSessionSave(save)
+			if tt.path == "" {
+				if err != nil {
+					t.Errorf("expected valid save, got %s", err)
+				}
+				return
+			}
+
+			schemaErr, ok := err.(*SchemaError)
+			if !ok {
+				t.Fatalf("expected schema error at %s, got %v", tt.path, err)
+			}
+
+			if schemaErr.Path != tt.path {
+				t.Errorf("expected error at %s, got %s", tt.path, schemaErr)
+			}
+		})
+	}
+}
+
+func TestValidateSystemSave(t *testing.T) {
+	tests := []struct {
+		name string
+		save string
+		path string
+	}{
+		{
+			name: "valid",
+			save: `{"gameVersion": "1.12.0.1", "gameStats": {"playTime": 3600, "battles": 12}, "dexData": {"1": {"seenAttr": 17, "caughtAttr": "340282366920938463463374607431768211523"}}}`,
+		},
+		{
+			name: "no game stats",
+			save: `{"dexData": {}}`,
+			path: "gameStats",
+		},
+		{
+			name: "no play time",
+			save: `{"gameStats": {"battles": 12}}`,
+			path: "gameStats.playTime",
+		},
+		{
+			name: "non-numeric stat",
+			save: `{"gameStats": {"playTime": 1, "battles": "12"}}`,
+			path: "gameStats.battles",
+		},
+		{
+			name: "negative attr",
+			save: `{"gameStats": {"playTime": 1}, "dexData": {"1": {"seenAttr": 1, "caughtAttr": 1}, "25": {"seenAttr": -1, "caughtAttr": 0}}}`,
+			path: "dexData.25.seenAttr",
+		},
+		{
+			name: "attr not a number",
+			save: `{"gameStats": {"playTime": 1}, "dexData": {"4": {"seenAttr": 1, "caughtAttr": "0x10"}}}`,
+			path: "dexData.4.caughtAttr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var save defs.SystemSaveData
+			if err := json.Unmarshal([]byte(tt.save), &save); err != nil {
+				t.Fatalf("failed to decode save: %s", err)
+			}
+
+			err := ValidateSystemSave(save)
+			if tt.path == "" {
+				if err != nil {
+					t.Errorf("expected valid save, got %s", err)
+				}
+				return
+			}
+
+			schemaErr, ok := err.(*SchemaError)
+			if !ok {
+				t.Fatalf("expected schema error at %s, got %v", tt.path, err)
+			}
+
+			if schemaErr.Path != tt.path {
+				t.Errorf("expected error at %s, got %s", tt.path, schemaErr)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ package savedata
 import (
 	"database/sql"
 	"errors"
+	"log"
 
 	"github.com/pagefaultgames/rogueserver/defs"
 )
@@ -56,11 +57,37 @@ func UpdateSession[T UpdateSessionStore](store T, uuid []byte, slot int, data de
 }
 
 type DeleteSessionStore interface {
+	ReadSessionSaveData(uuid []byte, slot int) (defs.SessionSaveData, error)
 	DeleteSessionSaveData(uuid []byte, slot int) error
+	AddSessionLedgerEntry(uuid []byte, seed string, entry defs.SessionLedgerEntry) error
+	ArchiveStore
 }
 
+// DeleteSession deletes a session that is being abandoned, archiving it
+// first. The ledger of an abandoned daily or event run is closed like that of
+// a cleared one, so playing the seed again starts a new ledger.
 func DeleteSession[T DeleteSessionStore](store T, uuid []byte, slot int) error {
-	err := store.DeleteSessionSaveData(uuid, slot)
+	session, err := store.ReadSessionSaveData(uuid, slot)
+	if err == nil {
+		err = archiveSession(store, uuid, session, defs.SessionHistoryResultAbandon)
+		if err != nil {
+			log.Printf("failed to archive session: %s", err)
+		}
+
+		if session.GameMode == 3 {
+			entry := newLedgerEntry(session)
+			entry.Final = true
+
+			err = store.AddSessionLedgerEntry(uuid, session.Seed, entry)
+			if err != nil {
+				log.Printf("failed to close run ledger: %s", err)
+			}
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to read session for archiving: %s", err)
+	}
+
+	err = store.DeleteSessionSaveData(uuid, slot)
 	if err != nil {
 		return err
 	}
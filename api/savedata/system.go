@@ -20,14 +20,14 @@ package savedata
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"os"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
-var ErrSaveNotExist = errors.New("save does not exist")
+var ErrSaveNotExist = apierror.ErrSaveNotFound
 
 type GetSystemStore interface {
 	GetSystemSaveFromS3(uuid []byte) (defs.SystemSaveData, error)
@@ -60,18 +60,25 @@ func GetSystem[T GetSystemStore](store T, uuid []byte) (defs.SystemSaveData, err
 // Interface for database operations needed for updating system data.
 type UpdateSystemStore interface {
 	UpdateAccountStats(uuid []byte, stats defs.GameStats, voucherCounts map[string]int) error
+	AddSystemLedgerEntry(uuid []byte, entry defs.SystemLedgerEntry) error
+	GetSystemStore
 	StoreSystemSaveDataS3(uuid []byte, data defs.SystemSaveData) error
 	StoreSystemSaveData(uuid []byte, data defs.SystemSaveData) error
 }
 
 func UpdateSystem[T UpdateSystemStore](store T, uuid []byte, data defs.SystemSaveData) error {
 	if data.TrainerId == 0 && data.SecretId == 0 {
-		return fmt.Errorf("invalid system data")
+		return apierror.ErrInvalidSaveData.Withf("invalid system data")
 	}
 
-	err := store.UpdateAccountStats(uuid, data.GameStats, data.VoucherCounts)
+	err := recordSystemLedger(store, uuid, data)
 	if err != nil {
-		return fmt.Errorf("failed to update account stats: %s", err)
+		return err
+	}
+
+	err = store.UpdateAccountStats(uuid, data.GameStats, data.VoucherCounts)
+	if err != nil {
+		return apierror.Internalf("failed to update account stats: %s", err)
 	}
 
 	if os.Getenv("S3_SYSTEM_BUCKET_NAME") != "" { // use S3
@@ -0,0 +1,250 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Limits used by the system checks. Like the run checks, they only flag an
+// update for review.
+var (
+	MaxEggs         = 100 // the gacha stops at 99, runs can award one more
+	MaxVoucherGain  = 25  // per voucher type and update
+	MaxEggTimeAhead = time.Hour
+)
+
+// eggSourceGachaMax is the last egg source type that is a gacha pull.
+const eggSourceGachaMax = 2
+
+// gachaPullsPerVoucher is the number of eggs a voucher of each type pulls
+// from the gacha, keyed like VoucherCounts.
+var gachaPullsPerVoucher = map[string]int{
+	"0": 1,
+	"1": 5,
+	"2": 10,
+	"3": 25,
+}
+
+// A SystemCheck inspects the change between an account's stored system save
+// and an update, and returns a reason when the change looks tampered with, or
+// an empty string otherwise.
+type SystemCheck func(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string
+
+// SystemChecks are run against every system save update that changes the
+// account's eggs or vouchers. Their reasons flag the update.
+var SystemChecks = []SystemCheck{
+	checkEggCount,
+	checkEggIds,
+	checkEggTimestamps,
+	checkVouchers,
+}
+
+// AdvisorySystemChecks are run like SystemChecks, but can't tell tampering
+// apart from honest play. Their reasons are kept as notes for reviewers of
+// flagged updates and don't flag the update themselves.
+var AdvisorySystemChecks = []SystemCheck{
+	checkGachaEggs,
+}
+
+func checkEggCount(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string {
+	if len(new.Eggs) > MaxEggs {
+		return fmt.Sprintf("eggs: %d eggs exceeds the maximum of %d", len(new.Eggs), MaxEggs)
+	}
+
+	return ""
+}
+
+func checkEggIds(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string {
+	seen := make(map[int]bool, len(new.Eggs))
+	for _, egg := range new.Eggs {
+		if seen[egg.Id] {
+			return fmt.Sprintf("eggs: duplicate egg id %d", egg.Id)
+		}
+
+		seen[egg.Id] = true
+	}
+
+	return ""
+}
+
+// checkGachaEggs compares the gacha eggs added against the vouchers spent. Only
+// the net change in vouchers between two saves is known, so vouchers earned
+// and spent between them go unseen and the check is advisory.
+func checkGachaEggs(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string {
+	var pulled int
+	for _, egg := range entry.EggsAdded {
+		if egg.SourceType <= eggSourceGachaMax {
+			pulled++
+		}
+	}
+
+	if pulled == 0 {
+		return ""
+	}
+
+	var paid int
+	for voucher, delta := range entry.Vouchers {
+		if delta < 0 {
+			paid += -delta * gachaPullsPerVoucher[voucher]
+		}
+	}
+
+	if pulled > paid {
+		return fmt.Sprintf("eggs: %d gacha eggs added with %d pulls paid for", pulled, paid)
+	}
+
+	return ""
+}
+
+func checkEggTimestamps(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string {
+	limit := time.Now().Add(MaxEggTimeAhead).UnixMilli()
+	for _, egg := range entry.EggsAdded {
+		if int64(egg.Timestamp) > limit {
+			return fmt.Sprintf("eggs: egg %d created in the future", egg.Id)
+		}
+	}
+
+	return ""
+}
+
+func checkVouchers(old, new defs.SystemSaveData, entry defs.SystemLedgerEntry) string {
+	for voucher, count := range new.VoucherCounts {
+		if count < 0 {
+			return fmt.Sprintf("vouchers: negative count of %d for type %s", count, voucher)
+		}
+	}
+
+	for voucher, delta := range entry.Vouchers {
+		if delta > MaxVoucherGain {
+			return fmt.Sprintf("vouchers: gained %d of type %s", delta, voucher)
+		}
+	}
+
+	return ""
+}
+
+// newSystemLedgerEntry diffs the eggs and vouchers of two system saves and
+// runs the system checks against the change. It reports false if nothing the
+// ledger tracks changed.
+func newSystemLedgerEntry(old, new defs.SystemSaveData) (defs.SystemLedgerEntry, bool) {
+	entry := defs.SystemLedgerEntry{
+		EggsAdded:      []defs.EggData{},
+		EggsRemoved:    []int{},
+		Vouchers:       make(map[string]int),
+		VoucherUnlocks: []string{},
+		Anomalies:      []string{},
+		Notes:          []string{},
+	}
+
+	oldEggs := make(map[int]bool, len(old.Eggs))
+	for _, egg := range old.Eggs {
+		oldEggs[egg.Id] = true
+	}
+
+	newEggs := make(map[int]bool, len(new.Eggs))
+	for _, egg := range new.Eggs {
+		newEggs[egg.Id] = true
+		if !oldEggs[egg.Id] {
+			entry.EggsAdded = append(entry.EggsAdded, egg)
+		}
+	}
+
+	for _, egg := range old.Eggs {
+		if !newEggs[egg.Id] {
+			entry.EggsRemoved = append(entry.EggsRemoved, egg.Id)
+		}
+	}
+
+	for voucher, count := range new.VoucherCounts {
+		if delta := count - old.VoucherCounts[voucher]; delta != 0 {
+			entry.Vouchers[voucher] = delta
+		}
+	}
+
+	for voucher, count := range old.VoucherCounts {
+		if _, ok := new.VoucherCounts[voucher]; !ok && count != 0 {
+			entry.Vouchers[voucher] = -count
+		}
+	}
+
+	for voucher := range new.VoucherUnlocks {
+		if _, ok := old.VoucherUnlocks[voucher]; !ok {
+			entry.VoucherUnlocks = append(entry.VoucherUnlocks, voucher)
+		}
+	}
+
+	slices.Sort(entry.VoucherUnlocks)
+
+	if len(entry.EggsAdded) == 0 && len(entry.EggsRemoved) == 0 && len(entry.Vouchers) == 0 && len(entry.VoucherUnlocks) == 0 {
+		return entry, false
+	}
+
+	for _, check := range SystemChecks {
+		if reason := check(old, new, entry); reason != "" {
+			entry.Anomalies = append(entry.Anomalies, reason)
+		}
+	}
+
+	for _, check := range AdvisorySystemChecks {
+		if reason := check(old, new, entry); reason != "" {
+			entry.Notes = append(entry.Notes, reason)
+		}
+	}
+
+	return entry, true
+}
+
+// recordSystemLedger diffs a system save update against the stored save and
+// records the change in the account's system ledger. Anomalies are logged but
+// don't block the update.
+func recordSystemLedger[T UpdateSystemStore](store T, uuid []byte, data defs.SystemSaveData) error {
+	old, err := GetSystem(store, uuid)
+	if err != nil {
+		// nothing to diff against on the first upload
+		if errors.Is(err, ErrSaveNotExist) {
+			return nil
+		}
+
+		return apierror.Internalf("failed to read system data: %s", err)
+	}
+
+	entry, changed := newSystemLedgerEntry(old, data)
+	if !changed {
+		return nil
+	}
+
+	err = store.AddSystemLedgerEntry(uuid, entry)
+	if err != nil {
+		return apierror.Internalf("failed to add system ledger entry: %s", err)
+	}
+
+	if len(entry.Anomalies) > 0 {
+		log.Printf("flagged system update of %x: %s", uuid, strings.Join(entry.Anomalies, "; "))
+	}
+
+	return nil
+}
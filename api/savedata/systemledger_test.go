@@ -0,0 +1,128 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func Test_newSystemLedgerEntry(t *testing.T) {
+	egg := func(id, sourceType int) defs.EggData {
+		return defs.EggData{Id: id, SourceType: sourceType, Timestamp: int(time.Now().UnixMilli())}
+	}
+
+	old := defs.SystemSaveData{
+		Eggs:          []defs.EggData{egg(1, 0), egg(2, 4)},
+		VoucherCounts: defs.VoucherCounts{"0": 10, "1": 2},
+	}
+
+	tests := []struct {
+		name      string
+		new       defs.SystemSaveData
+		changed   bool
+		anomalies []string
+		notes     []string
+	}{
+		{
+			name: "unchanged",
+			new:  old,
+		},
+		{
+			name: "pulls paid with vouchers",
+			new: defs.SystemSaveData{
+				Eggs:          []defs.EggData{egg(2, 4), egg(3, 0), egg(4, 1), egg(5, 2), egg(6, 0), egg(7, 0), egg(8, 0)},
+				VoucherCounts: defs.VoucherCounts{"0": 9, "1": 1},
+			},
+			changed: true,
+		},
+		{
+			name: "eggs from runs",
+			new: defs.SystemSaveData{
+				Eggs:          []defs.EggData{egg(1, 0), egg(2, 4), egg(3, 3), egg(4, 4)},
+				VoucherCounts: old.VoucherCounts,
+			},
+			changed: true,
+		},
+		{
+			name: "pulls without vouchers",
+			new: defs.SystemSaveData{
+				Eggs:          []defs.EggData{egg(1, 0), egg(2, 4), egg(3, 0), egg(4, 0)},
+				VoucherCounts: defs.VoucherCounts{"0": 9, "1": 2},
+			},
+			changed: true,
+			notes:   []string{"eggs: 2 gacha eggs added with 1 pulls paid for"},
+		},
+		{
+			name: "voucher earned and spent between saves",
+			new: defs.SystemSaveData{
+				Eggs:          []defs.EggData{egg(1, 0), egg(2, 4), egg(3, 0)},
+				VoucherCounts: old.VoucherCounts,
+			},
+			changed: true,
+			notes:   []string{"eggs: 1 gacha eggs added with 0 pulls paid for"},
+		},
+		{
+			name: "voucher jump",
+			new: defs.SystemSaveData{
+				Eggs:          old.Eggs,
+				VoucherCounts: defs.VoucherCounts{"0": 10, "1": 2, "3": 99},
+			},
+			changed:   true,
+			anomalies: []string{"vouchers: gained 99 of type 3"},
+		},
+		{
+			name: "egg from the future",
+			new: defs.SystemSaveData{
+				Eggs:          []defs.EggData{egg(1, 0), egg(2, 4), {Id: 3, SourceType: 4, Timestamp: int(time.Now().Add(48 * time.Hour).UnixMilli())}},
+				VoucherCounts: old.VoucherCounts,
+			},
+			changed:   true,
+			anomalies: []string{"eggs: egg 3 created in the future"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, changed := newSystemLedgerEntry(old, tt.new)
+			if changed != tt.changed {
+				t.Fatalf("expected changed %v, got %v", tt.changed, changed)
+			}
+
+			if !changed {
+				return
+			}
+
+			if strings.Join(entry.Anomalies, "; ") != strings.Join(tt.anomalies, "; ") {
+				t.Errorf("expected anomalies %q, got %q", tt.anomalies, entry.Anomalies)
+			}
+
+			if strings.Join(entry.Notes, "; ") != strings.Join(tt.notes, "; ") {
+				t.Errorf("expected notes %q, got %q", tt.notes, entry.Notes)
+			}
+		})
+	}
+
+	entry, _ := newSystemLedgerEntry(old, tests[1].new)
+	if len(entry.EggsAdded) != 6 || len(entry.EggsRemoved) != 1 || entry.EggsRemoved[0] != 1 || entry.Vouchers["0"] != -1 || entry.Vouchers["1"] != -1 {
+		t.Errorf("unexpected diff %+v", entry)
+	}
+}
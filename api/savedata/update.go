@@ -18,9 +18,9 @@
 package savedata
 
 import (
-	"fmt"
 	"log"
 
+	"github.com/pagefaultgames/rogueserver/api/apierror"
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
@@ -40,17 +40,17 @@ func Update[T UpdateStore](store T, uuid []byte, slot int, save any) error {
 	switch save := save.(type) {
 	case defs.SystemSaveData: // System
 		if save.TrainerId == 0 && save.SecretId == 0 {
-			return fmt.Errorf("invalid system data")
+			return apierror.ErrInvalidSaveData.Withf("invalid system data")
 		}
 
 		return UpdateSystem(store, uuid, save)
 	case defs.SessionSaveData: // Session
 		if slot < 0 || slot >= defs.SessionSlotCount {
-			return fmt.Errorf("slot id %d out of range", slot)
+			return apierror.ErrInvalidSlot.Withf("slot id %d out of range", slot)
 		}
 
 		return UpdateSession(store, uuid, slot, save)
 	default:
-		return fmt.Errorf("invalid data type")
+		return apierror.ErrInvalidDataType
 	}
 }
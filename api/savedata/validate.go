@@ -0,0 +1,269 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Limits used by the run checks. They are deliberately generous: a single
+// failed check holds the run back for review, so they are only meant to catch
+// saves the game could not have produced.
+var (
+	MaxDailyScore       = 20000
+	MaxScorePerVictory  = 500
+	MinSecondsPerWave   = 5
+	MaxPartySize        = 6
+	MaxLevelAboveWave   = 100 // rare candies can take levels past the wave's level cap
+	MaxLevelsPerWave    = 20
+	MaxMoneyPerWave     = 10000
+	MaxModifiersPerWave = 10
+)
+
+// A RunCheck inspects a daily run's session save and returns a reason when the
+// run looks tampered with, or an empty string otherwise. previous is the last
+// entry in the run's ledger, or nil if this is the first save of the run.
+//
+// Reasons start with the check's name followed by a colon, which is used to
+// avoid flagging a run twice for the same check.
+type RunCheck func(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string
+
+// RunChecks are run against every daily run save, on update and on clear.
+var RunChecks = []RunCheck{
+	checkWave,
+	checkScore,
+	checkPlayTime,
+	checkParty,
+	checkMoney,
+	checkModifiers,
+}
+
+func checkWave(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if previous != nil && save.WaveIndex < previous.Wave {
+		return fmt.Sprintf("wave: went back from %d to %d", previous.Wave, save.WaveIndex)
+	}
+
+	return ""
+}
+
+func checkScore(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if save.Score >= MaxDailyScore {
+		return fmt.Sprintf("score: %d exceeds the maximum of %d", save.Score, MaxDailyScore)
+	}
+
+	// a wave is at most a double battle
+	if save.VictoryCount > save.WaveIndex*2 {
+		return fmt.Sprintf("score: %d victories by wave %d", save.VictoryCount, save.WaveIndex)
+	}
+
+	if limit := (save.VictoryCount + 1) * MaxScorePerVictory; save.Score > limit {
+		return fmt.Sprintf("score: %d from %d victories", save.Score, save.VictoryCount)
+	}
+
+	if previous != nil {
+		if save.Score < previous.Score || save.VictoryCount < previous.VictoryCount {
+			return fmt.Sprintf("score: went back from %d (%d victories) to %d (%d victories)", previous.Score, previous.VictoryCount, save.Score, save.VictoryCount)
+		}
+
+		gained := save.Score - previous.Score
+		victories := save.VictoryCount - previous.VictoryCount
+		if gained > (victories+1)*MaxScorePerVictory {
+			return fmt.Sprintf("score: gained %d from %d victories between waves %d and %d", gained, victories, previous.Wave, save.WaveIndex)
+		}
+	}
+
+	return ""
+}
+
+func checkPlayTime(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if minimum := (save.WaveIndex - 1) * MinSecondsPerWave; save.PlayTime < minimum {
+		return fmt.Sprintf("playTime: reached wave %d in %d seconds", save.WaveIndex, save.PlayTime)
+	}
+
+	if previous != nil && save.PlayTime < previous.PlayTime {
+		return fmt.Sprintf("playTime: went back from %d to %d seconds", previous.PlayTime, save.PlayTime)
+	}
+
+	return ""
+}
+
+func checkParty(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if len(save.Party) == 0 || len(save.Party) > MaxPartySize {
+		return fmt.Sprintf("party: %d party members", len(save.Party))
+	}
+
+	party, ok := ledgerParty(save.Party)
+	if !ok {
+		return "party: malformed party member"
+	}
+
+	for _, pokemon := range party {
+		if pokemon.Level < 1 || pokemon.Level > save.WaveIndex+MaxLevelAboveWave {
+			return fmt.Sprintf("party: level %d at wave %d", pokemon.Level, save.WaveIndex)
+		}
+	}
+
+	if previous == nil {
+		return ""
+	}
+
+	// party members aren't tracked individually, so compare against the
+	// highest level the species had before; evolving changes the species and
+	// starts a fresh comparison
+	levels := make(map[int]int)
+	for _, pokemon := range previous.Party {
+		levels[pokemon.Species] = max(levels[pokemon.Species], pokemon.Level)
+	}
+
+	limit := (save.WaveIndex - previous.Wave + 1) * MaxLevelsPerWave
+	for _, pokemon := range party {
+		if level, ok := levels[pokemon.Species]; ok && pokemon.Level-level > limit {
+			return fmt.Sprintf("party: species %d went from level %d to %d between waves %d and %d", pokemon.Species, level, pokemon.Level, previous.Wave, save.WaveIndex)
+		}
+	}
+
+	return ""
+}
+
+func checkMoney(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if save.Money < 0 {
+		return fmt.Sprintf("money: negative balance of %d", save.Money)
+	}
+
+	if previous == nil {
+		return ""
+	}
+
+	// shops and encounters can pay out more than once on the same wave
+	gained := save.Money - previous.Money
+	if gained > (save.WaveIndex-previous.Wave+1)*MaxMoneyPerWave {
+		return fmt.Sprintf("money: gained %d between waves %d and %d", gained, previous.Wave, save.WaveIndex)
+	}
+
+	return ""
+}
+
+func checkModifiers(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) string {
+	if previous == nil {
+		return ""
+	}
+
+	gained := ledgerModifiers(save.Modifiers) - previous.Modifiers
+	if gained > (save.WaveIndex-previous.Wave+1)*MaxModifiersPerWave {
+		return fmt.Sprintf("modifiers: gained %d stacks between waves %d and %d", gained, previous.Wave, save.WaveIndex)
+	}
+
+	return ""
+}
+
+// validateRun runs the run checks against a daily run save and returns the
+// reasons of the checks that failed.
+func validateRun(previous *defs.SessionLedgerEntry, save defs.SessionSaveData) []string {
+	if save.GameMode != 3 {
+		return nil
+	}
+
+	var reasons []string
+	for _, check := range RunChecks {
+		if reason := check(previous, save); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return reasons
+}
+
+// Interface for database operations needed for validating runs.
+type ValidateRunStore interface {
+	FetchLastSessionLedgerEntry(uuid []byte, seed string) (defs.SessionLedgerEntry, error)
+	AddSessionLedgerEntry(uuid []byte, seed string, entry defs.SessionLedgerEntry) error
+	FetchRunFlag(uuid []byte, seed string) (defs.RunFlag, error)
+	StoreRunFlag(uuid []byte, seed string, score, wave int, reasons []string, session defs.SessionSaveData) error
+}
+
+// ValidateRun records a daily or event run's session save in its ledger and
+// checks the transition from the previous entry. Saves of other modes are
+// ignored, as only those runs make it onto a leaderboard. final marks the save
+// a run is cleared with, after which the seed starts a new ledger. Runs
+// failing any of the checks are flagged for review; it reports whether the
+// run is flagged and not yet approved, whether by this save or an earlier one.
+func ValidateRun[T ValidateRunStore](store T, uuid []byte, save defs.SessionSaveData, final bool) (bool, error) {
+	// event runs are played in daily mode too
+	if save.GameMode != 3 {
+		return false, nil
+	}
+
+	var previous *defs.SessionLedgerEntry
+	last, err := store.FetchLastSessionLedgerEntry(uuid, save.Seed)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	} else if !last.Final {
+		previous = &last
+	}
+
+	entry := newLedgerEntry(save)
+	entry.Final = final
+
+	err = store.AddSessionLedgerEntry(uuid, save.Seed, entry)
+	if err != nil {
+		return false, apierror.Internalf("failed to add ledger entry: %s", err)
+	}
+
+	reasons := validateRun(previous, save)
+
+	flag, err := store.FetchRunFlag(uuid, save.Seed)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	flagged := err == nil && flag.Status != defs.RunFlagApproved
+
+	var added bool
+	for _, reason := range reasons {
+		check, _, _ := strings.Cut(reason, ":")
+		if slices.ContainsFunc(flag.Reasons, func(existing string) bool { return strings.HasPrefix(existing, check+":") }) {
+			continue
+		}
+
+		flag.Reasons = append(flag.Reasons, reason)
+		added = true
+	}
+
+	if !added {
+		return flagged, nil
+	}
+
+	err = store.StoreRunFlag(uuid, save.Seed, save.Score, save.WaveIndex, flag.Reasons, save)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("flagged daily run %s of %x: %s", save.Seed, uuid, strings.Join(reasons, "; "))
+
+	return true, nil
+}
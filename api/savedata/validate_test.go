@@ -0,0 +1,195 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// run returns a daily run save of seed "seed" with a party of the given levels.
+func run(wave, score, victories, playTime, money int, levels ...float64) defs.SessionSaveData {
+	save := defs.SessionSaveData{
+		Seed:         "seed",
+		GameMode:     3,
+		WaveIndex:    wave,
+		Score:        score,
+		VictoryCount: victories,
+		PlayTime:     playTime,
+		Money:        money,
+	}
+	for _, level := range levels {
+		save.Party = append(save.Party, map[string]interface{}{"species": float64(1), "level": level})
+	}
+
+	return save
+}
+
+func Test_validateRun(t *testing.T) {
+	previous := newLedgerEntry(run(10, 1000, 10, 600, 5000, 15))
+
+	tests := []struct {
+		name     string
+		previous *defs.SessionLedgerEntry
+		save     defs.SessionSaveData
+		want     []string
+	}{
+		{
+			name: "plausible run",
+			save: run(10, 1000, 10, 600, 5000, 15, 12),
+		},
+		{
+			name: "not a daily run",
+			save: defs.SessionSaveData{GameMode: 0, Score: 50000},
+		},
+		{
+			name: "score over maximum",
+			save: run(10, 20000, 10, 600, 5000, 15),
+			want: []string{"score"},
+		},
+		{
+			name: "too fast",
+			save: run(50, 1000, 50, 30, 5000, 55),
+			want: []string{"playTime"},
+		},
+		{
+			name: "oversized party",
+			save: run(10, 1000, 10, 600, 5000, 1, 1, 1, 1, 1, 1, 1),
+			want: []string{"party"},
+		},
+		{
+			name:     "money jump",
+			previous: &previous,
+			save:     run(11, 1100, 11, 660, 500000, 16),
+			want:     []string{"money"},
+		},
+		{
+			name:     "plausible transition",
+			previous: &previous,
+			save:     run(12, 1200, 12, 720, 8000, 17),
+		},
+		{
+			name:     "wave went back",
+			previous: &previous,
+			save:     run(9, 1000, 10, 660, 5000, 15),
+			want:     []string{"wave"},
+		},
+		{
+			name:     "level jump",
+			previous: &previous,
+			save:     run(11, 1100, 11, 660, 5000, 95),
+			want:     []string{"party"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, reason := range validateRun(tt.previous, tt.save) {
+				check, _, _ := strings.Cut(reason, ":")
+				got = append(got, check)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("validateRun() = %v, want checks %v", validateRun(tt.previous, tt.save), tt.want)
+			}
+		})
+	}
+}
+
+type mockValidateRunStore struct {
+	ledger  []defs.SessionLedgerEntry
+	session *defs.SessionSaveData
+	flagged []string
+}
+
+func (m *mockValidateRunStore) FetchLastSessionLedgerEntry(uuid []byte, seed string) (defs.SessionLedgerEntry, error) {
+	if len(m.ledger) == 0 {
+		return defs.SessionLedgerEntry{}, sql.ErrNoRows
+	}
+	return m.ledger[len(m.ledger)-1], nil
+}
+func (m *mockValidateRunStore) AddSessionLedgerEntry(uuid []byte, seed string, entry defs.SessionLedgerEntry) error {
+	m.ledger = append(m.ledger, entry)
+	return nil
+}
+func (m *mockValidateRunStore) FetchRunFlag(uuid []byte, seed string) (defs.RunFlag, error) {
+	return defs.RunFlag{}, sql.ErrNoRows
+}
+func (m *mockValidateRunStore) StoreRunFlag(uuid []byte, seed string, score, wave int, reasons []string, session defs.SessionSaveData) error {
+	m.flagged = reasons
+	return nil
+}
+func (m *mockValidateRunStore) ReadSessionSaveData(uuid []byte, slot int) (defs.SessionSaveData, error) {
+	if m.session == nil {
+		return defs.SessionSaveData{}, sql.ErrNoRows
+	}
+	return *m.session, nil
+}
+func (m *mockValidateRunStore) DeleteSessionSaveData(uuid []byte, slot int) error {
+	m.session = nil
+	return nil
+}
+func (m *mockValidateRunStore) AddSessionHistory(uuid []byte, history defs.SessionHistoryData, maxRuns int) error {
+	return nil
+}
+
+func TestValidateRun(t *testing.T) {
+	store := &mockValidateRunStore{}
+	_, err := ValidateRun(store, nil, defs.SessionSaveData{Seed: "seed", GameMode: 0, WaveIndex: 10}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(store.ledger) != 0 {
+		t.Errorf("expected classic runs not to be recorded in the ledger, got %d entries", len(store.ledger))
+	}
+
+	_, err = ValidateRun(store, nil, defs.SessionSaveData{Seed: "seed", GameMode: 3, WaveIndex: 10}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(store.ledger) != 1 {
+		t.Errorf("expected daily runs to be recorded in the ledger, got %d entries", len(store.ledger))
+	}
+}
+
+func TestValidateRunAfterAbandon(t *testing.T) {
+	store := &mockValidateRunStore{}
+
+	save := run(40, 4000, 40, 2400, 20000, 45)
+	if _, err := ValidateRun(store, nil, save, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	store.session = &save
+	if err := DeleteSession(store, nil, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the same seed played again from the start
+	restart := run(1, 10, 1, 60, 1000, 5)
+	flagged, err := ValidateRun(store, nil, restart, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if flagged || store.flagged != nil {
+		t.Errorf("expected a restarted run not to be checked against the abandoned one, got %v", store.flagged)
+	}
+}
@@ -0,0 +1,278 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package api
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/api/daily"
+	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/db"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// Save handling shared by the v1 and v2 save endpoints. Errors are apierrors
+// carrying their own status.
+
+// minSaveGameVersion is the oldest game version whose saves are accepted over
+// an existing save.
+const minSaveGameVersion = "1.12.0.1"
+
+func parseSlot(value string) (int, error) {
+	slot, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, apierror.ErrInvalidSlot.Withf("failed to convert slot: %s", err)
+	}
+
+	if slot < 0 || slot >= defs.SessionSlotCount {
+		return 0, apierror.ErrInvalidSlot.Withf("slot id %d out of range", slot)
+	}
+
+	return slot, nil
+}
+
+// checkActiveSession rejects saves from a client whose session has been taken
+// over by another.
+func checkActiveSession(uuid []byte, clientSessionId string) error {
+	if clientSessionId == "" {
+		return apierror.ErrMissingClientSessionId
+	}
+
+	active, err := db.Store.IsActiveSession(uuid, clientSessionId)
+	if err != nil {
+		return apierror.Internalf("failed to check active session: %s", err)
+	}
+
+	if !active {
+		return apierror.ErrSessionOutOfDate.Withf("session out of date: not active")
+	}
+
+	return nil
+}
+
+// checkSessionUpdate rejects a session save that is behind the stored one.
+func checkSessionUpdate(uuid []byte, slot int, session defs.SessionSaveData) error {
+	existingSave, err := savedata.GetSession(db.Store, uuid, slot)
+	if err != nil {
+		if errors.Is(err, savedata.ErrSaveNotExist) {
+			return nil
+		}
+
+		return apierror.Internalf("failed to retrieve session save data: %s", err)
+	}
+
+	if existingSave.Seed == session.Seed && existingSave.WaveIndex > session.WaveIndex {
+		return apierror.ErrSessionOutOfDate.Withf("session out of date: existing wave index is greater")
+	}
+
+	return nil
+}
+
+// checkSystemUpdate rejects a system save that is behind the stored one or
+// from a game version that is no longer supported.
+func checkSystemUpdate(uuid []byte, system defs.SystemSaveData) error {
+	oldSystem, err := savedata.GetSystem(db.Store, uuid)
+	if err != nil {
+		if errors.Is(err, savedata.ErrSaveNotExist) {
+			return nil
+		}
+
+		return apierror.Internalf("failed to retrieve playtime: %s", err)
+	}
+
+	playtime, ok := savedata.PlayTime(system)
+	if !ok {
+		return apierror.ErrInvalidSaveData.Withf("no playtime found")
+	}
+
+	oldPlaytime, ok := savedata.PlayTime(oldSystem)
+	if !ok {
+		return apierror.ErrInvalidSaveData.Withf("no playtime found")
+	}
+
+	if playtime < oldPlaytime {
+		return apierror.ErrSessionOutOfDate.Withf("session out of date: existing playtime is greater")
+	}
+
+	minVerCmp, err := savedata.CompareGameVersion(minSaveGameVersion, system.GameVersion)
+	if err != nil {
+		return apierror.ErrInvalidGameVersion.Withf("failed to compare versions: %s", err)
+	}
+
+	if minVerCmp > 0 {
+		return apierror.ErrSessionOutOfDate.Withf("session out of date: save version below minimum game version")
+	}
+
+	saveVerCmp, err := savedata.CompareGameVersion(oldSystem.GameVersion, system.GameVersion)
+	if err != nil {
+		return apierror.ErrInvalidGameVersion.Withf("failed to compare versions: %s", err)
+	}
+
+	if saveVerCmp > 0 {
+		return apierror.ErrSessionOutOfDate.Withf("session out of date: existing version is greater")
+	}
+
+	return nil
+}
+
+// saveSession stores a session save in slot after checking it.
+func saveSession(uuid []byte, slot int, session defs.SessionSaveData) error {
+	err := savedata.ValidateSessionSave(session)
+	if err != nil {
+		return apierror.ErrInvalidSaveData.Withf("invalid session data: %s", err)
+	}
+
+	err = checkSessionUpdate(uuid, slot, session)
+	if err != nil {
+		return err
+	}
+
+	_, err = savedata.ValidateRun(db.Store, uuid, session, false)
+	if err != nil {
+		log.Printf("failed to validate run: %s", err)
+	}
+
+	err = savedata.UpdateSession(db.Store, uuid, slot, session)
+	if err != nil {
+		return apierror.Internalf("failed to put session data: %s", err)
+	}
+
+	return nil
+}
+
+// clearSession ends the run saved in slot with the final state of session.
+func clearSession(uuid []byte, slot int, session defs.SessionSaveData) (savedata.ClearResponse, error) {
+	err := savedata.ValidateSessionSave(session)
+	if err != nil {
+		return savedata.ClearResponse{}, apierror.ErrInvalidSaveData.Withf("invalid session data: %s", err)
+	}
+
+	date := daily.Today()
+	seed, err := db.Store.GetDailyRunSeed(date)
+	if err != nil {
+		return savedata.ClearResponse{}, apierror.Internalf("failed to fetch daily seed: %s", err)
+	}
+
+	return savedata.Clear(clearStore{db.Store}, uuid, slot, date, seed, session)
+}
+
+// saveSystem stores a system save after checking it.
+func saveSystem(uuid []byte, system defs.SystemSaveData) error {
+	err := savedata.ValidateSystemSave(system)
+	if err != nil {
+		return apierror.ErrInvalidSaveData.Withf("invalid system data: %s", err)
+	}
+
+	err = checkSystemUpdate(uuid, system)
+	if err != nil {
+		return err
+	}
+
+	err = savedata.UpdateSystem(db.Store, uuid, system)
+	if err != nil {
+		return apierror.Internalf("failed to put system data: %s", err)
+	}
+
+	return nil
+}
+
+// saveAll stores a system save and a session save together.
+// TODO wrap this in a transaction
+func saveAll(uuid []byte, data CombinedSaveData) error {
+	err := savedata.ValidateSystemSave(data.System)
+	if err != nil {
+		return apierror.ErrInvalidSaveData.Withf("invalid system data: %s", err)
+	}
+
+	err = savedata.ValidateSessionSave(data.Session)
+	if err != nil {
+		return apierror.ErrInvalidSaveData.Withf("invalid session data: %s", err)
+	}
+
+	err = checkActiveSession(uuid, data.ClientSessionId)
+	if err != nil {
+		return err
+	}
+
+	storedTrainerId, storedSecretId, err := db.Store.FetchTrainerIds(uuid)
+	if err != nil {
+		return apierror.Internalf("failed to fetch trainer ids: %s", err)
+	}
+
+	if storedTrainerId > 0 || storedSecretId > 0 {
+		if data.System.TrainerId != storedTrainerId || data.System.SecretId != storedSecretId {
+			return apierror.ErrSessionOutOfDate.Withf("session out of date: stored trainer or secret ID does not match")
+		}
+	} else {
+		err = db.Store.UpdateTrainerIds(data.System.TrainerId, data.System.SecretId, uuid)
+		if err != nil {
+			return apierror.Internalf("failed to update trainer ids: %s", err)
+		}
+	}
+
+	err = checkSystemUpdate(uuid, data.System)
+	if err != nil {
+		return err
+	}
+
+	err = checkSessionUpdate(uuid, data.SessionSlotId, data.Session)
+	if err != nil {
+		return err
+	}
+
+	_, err = savedata.ValidateRun(db.Store, uuid, data.Session, false)
+	if err != nil {
+		log.Printf("failed to validate run: %s", err)
+	}
+
+	err = savedata.Update(db.Store, uuid, data.SessionSlotId, data.Session)
+	if err != nil {
+		return err
+	}
+
+	return savedata.Update(db.Store, uuid, 0, data.System)
+}
+
+// verifySystem reports whether clientSessionId is the active session. If it
+// isn't, it takes over and the stored system save is returned to resync from.
+func verifySystem(uuid []byte, clientSessionId string) (SystemVerifyResponse, error) {
+	active, err := db.Store.IsActiveSession(uuid, clientSessionId)
+	if err != nil {
+		return SystemVerifyResponse{}, apierror.Internalf("failed to check active session: %s", err)
+	}
+
+	response := SystemVerifyResponse{Valid: active}
+	if active {
+		return response, nil
+	}
+
+	err = db.Store.UpdateActiveSession(uuid, clientSessionId)
+	if err != nil {
+		return response, apierror.Internalf("failed to update active session: %s", err)
+	}
+
+	response.SystemData, err = db.Store.ReadSystemSaveData(uuid)
+	if err != nil {
+		return response, apierror.Internalf("failed to read session save data: %s", err)
+	}
+
+	return response, nil
+}
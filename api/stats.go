@@ -18,41 +18,66 @@
 package api
 
 import (
+	"log"
 	"time"
 
+	"github.com/pagefaultgames/rogueserver/api/daily"
+	"github.com/pagefaultgames/rogueserver/api/stats"
 	"github.com/robfig/cron/v3"
 )
 
-var (
-	scheduler           = cron.New(cron.WithLocation(time.UTC))
-	playerCount         int
-	battleCount         int
-	classicSessionCount int
-)
+var scheduler = cron.New(cron.WithLocation(time.UTC))
 
 func scheduleStatRefresh[T updateStatsStore](store T) error {
-	_, err := scheduler.AddFunc("@every 1m", func() { playerCount, _ = store.FetchPlayerCount() })
+	refreshPlayerCount := func() {
+		err := stats.RefreshPlayerCount(store)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+
+	refreshTotals := func() {
+		err := stats.RefreshTotals(store, daily.Today())
+		if err != nil {
+			log.Print(err)
+		}
+	}
+
+	_, err := scheduler.AddFunc("@every 1m", refreshPlayerCount)
 	if err != nil {
 		return err
 	}
 
-	_, err = scheduler.AddFunc("@every 1h", func() { battleCount, _ = store.FetchBattleCount() })
+	_, err = scheduler.AddFunc("@every 1h", refreshTotals)
 	if err != nil {
 		return err
 	}
 
-	_, err = scheduler.AddFunc("@every 1h", func() { classicSessionCount, _ = store.FetchClassicSessionCount() })
+	// snapshot just before the hour, so the last snapshot of a day is taken
+	// at 23:59
+	_, err = scheduler.AddFunc("59 * * * *", func() {
+		err := stats.Snapshot(store, time.Now(), daily.Today())
+		if err != nil {
+			log.Printf("failed to take stats snapshot: %s", err)
+		}
+	})
 	if err != nil {
 		return err
 	}
 
+	// don't serve zeroes until the first refresh
+	go func() {
+		refreshPlayerCount()
+		refreshTotals()
+	}()
+
 	scheduler.Start()
 
 	return nil
 }
 
 type updateStatsStore interface {
-	FetchPlayerCount() (int, error)
-	FetchBattleCount() (int, error)
-	FetchClassicSessionCount() (int, error)
+	stats.PlayerCountStore
+	stats.TotalsStore
+	stats.SnapshotStore
 }
@@ -0,0 +1,259 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// DexAttr bits of a dex entry's seenAttr and caughtAttr, as defined by the
+// game.
+const (
+	dexAttrShiny    = 1
+	dexAttrVariant2 = 5
+	dexAttrVariant3 = 6
+)
+
+// passiveAttrUnlocked is the PassiveAttr bit set once a starter's passive is
+// unlocked.
+const passiveAttrUnlocked = 1
+
+const dexStatsBatchSize = 500
+
+var (
+	// DexStatsWindow is how recently players must have been active to be
+	// counted by the dex stats. Long inactive saves would otherwise hold the
+	// percentages back.
+	DexStatsWindow = 30 * 24 * time.Hour
+
+	// DexStatsRefresh is how long the results of the last run are served
+	// from the cache before being read again.
+	DexStatsRefresh = time.Hour
+)
+
+var dexStats = struct {
+	mu       sync.Mutex
+	stats    *defs.DexStats
+	loadedAt time.Time
+}{}
+
+// dexAttr reads a dex attribute, which the game sends as a number or, once it
+// outgrows a float, as a string.
+func dexAttr(value any) *big.Int {
+	attr := new(big.Int)
+	switch value := value.(type) {
+	case float64:
+		big.NewFloat(value).Int(attr)
+	case int:
+		attr.SetInt64(int64(value))
+	case string:
+		if _, ok := attr.SetString(value, 10); !ok {
+			attr.SetInt64(0)
+		}
+	}
+
+	return attr
+}
+
+// dexCounter tallies the dex and starter data of system saves.
+type dexCounter struct {
+	players int
+	species map[int]*defs.DexSpeciesCounts
+}
+
+func newDexCounter() *dexCounter {
+	return &dexCounter{species: make(map[int]*defs.DexSpeciesCounts)}
+}
+
+func (c *dexCounter) get(species int) *defs.DexSpeciesCounts {
+	counts, ok := c.species[species]
+	if !ok {
+		counts = &defs.DexSpeciesCounts{Species: species}
+		c.species[species] = counts
+	}
+
+	return counts
+}
+
+func (c *dexCounter) add(system defs.SystemSaveData) {
+	c.players++
+
+	for species, entry := range system.DexData {
+		counts := c.get(species)
+
+		if entry.SeenCount > 0 || dexAttr(entry.SeenAttr).Sign() != 0 {
+			counts.Seen++
+		}
+
+		caught := dexAttr(entry.CaughtAttr)
+		if caught.Sign() != 0 {
+			counts.Caught++
+		}
+
+		if entry.HatchedCount > 0 {
+			counts.Hatched++
+		}
+
+		if caught.Bit(dexAttrShiny) != 0 {
+			counts.Shiny++
+		}
+
+		if caught.Bit(dexAttrVariant2) != 0 {
+			counts.Variant2++
+		}
+
+		if caught.Bit(dexAttrVariant3) != 0 {
+			counts.Variant3++
+		}
+	}
+
+	for species, entry := range system.StarterData {
+		counts := c.get(species)
+
+		if entry.ClassicWinCount > 0 {
+			counts.ClassicWins++
+		}
+
+		if entry.PassiveAttr&passiveAttrUnlocked != 0 {
+			counts.Passive++
+		}
+	}
+}
+
+func (c *dexCounter) counts() []defs.DexSpeciesCounts {
+	counts := make([]defs.DexSpeciesCounts, 0, len(c.species))
+	for _, species := range c.species {
+		counts = append(counts, *species)
+	}
+
+	slices.SortFunc(counts, func(a, b defs.DexSpeciesCounts) int { return a.Species - b.Species })
+
+	return counts
+}
+
+// Interface for database operations needed for aggregating dex stats.
+type AggregateDexStore interface {
+	FetchActiveAccountsAfter(after []byte, since time.Time, limit int) ([][]byte, error)
+	StoreDexStats(players int, counts []defs.DexSpeciesCounts) error
+	savedata.GetSystemStore
+}
+
+// AggregateDex tallies the dex and starter data of every recently active
+// player and stores the results. Saves that fail to load are skipped.
+func AggregateDex[T AggregateDexStore](store T) error {
+	counter := newDexCounter()
+	since := time.Now().UTC().Add(-DexStatsWindow)
+
+	after := []byte{}
+	for {
+		uuids, err := store.FetchActiveAccountsAfter(after, since, dexStatsBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch active accounts: %s", err)
+		}
+
+		for _, uuid := range uuids {
+			system, err := savedata.GetSystem(store, uuid)
+			if err != nil {
+				if !errors.Is(err, savedata.ErrSaveNotExist) {
+					log.Printf("failed to read system save of %x for dex stats: %s", uuid, err)
+				}
+
+				continue
+			}
+
+			counter.add(system)
+		}
+
+		if len(uuids) < dexStatsBatchSize {
+			break
+		}
+
+		after = uuids[len(uuids)-1]
+	}
+
+	err := store.StoreDexStats(counter.players, counter.counts())
+	if err != nil {
+		return fmt.Errorf("failed to store dex stats: %s", err)
+	}
+
+	return nil
+}
+
+func percentage(count, players int) float64 {
+	if players == 0 {
+		return 0
+	}
+
+	return float64(count) * 100 / float64(players)
+}
+
+// Interface for database operations needed for fetching dex stats.
+type DexStatsStore interface {
+	FetchDexStats() (int, string, []defs.DexSpeciesCounts, error)
+}
+
+// /game/dexstats - fetch the percentage of players that have seen, caught,
+// hatched, won classic with and unlocked the passive of each species, as of
+// the last aggregation run
+func DexStats[T DexStatsStore](store T) (defs.DexStats, error) {
+	dexStats.mu.Lock()
+	defer dexStats.mu.Unlock()
+
+	if dexStats.stats != nil && time.Since(dexStats.loadedAt) < DexStatsRefresh {
+		return *dexStats.stats, nil
+	}
+
+	players, computed, counts, err := store.FetchDexStats()
+	if err != nil {
+		// nothing to serve until the first aggregation run
+		if errors.Is(err, sql.ErrNoRows) {
+			return defs.DexStats{Species: []defs.DexSpeciesStats{}}, nil
+		}
+
+		return defs.DexStats{}, err
+	}
+
+	stats := defs.DexStats{Players: players, Computed: computed, Species: make([]defs.DexSpeciesStats, 0, len(counts))}
+	for _, species := range counts {
+		stats.Species = append(stats.Species, defs.DexSpeciesStats{
+			Species:    species.Species,
+			Seen:       percentage(species.Seen, players),
+			Caught:     percentage(species.Caught, players),
+			Hatched:    percentage(species.Hatched, players),
+			Shiny:      percentage(species.Shiny, players),
+			Variant2:   percentage(species.Variant2, players),
+			Variant3:   percentage(species.Variant3, players),
+			ClassicWin: percentage(species.ClassicWins, players),
+			Passive:    percentage(species.Passive, players),
+		})
+	}
+
+	dexStats.stats, dexStats.loadedAt = &stats, time.Now()
+
+	return stats, nil
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func TestDexAttr(t *testing.T) {
+	tests := []struct {
+		value any
+		shiny bool
+		epic  bool
+	}{
+		{float64(1 | 16 | 128), false, false},
+		{float64(2 | 64 | 128), true, true},
+		{"340282366920938463463374607431768211523", true, true}, // 2^128 + 67
+		{"not a number", false, false},
+		{nil, false, false},
+	}
+
+	for _, test := range tests {
+		attr := dexAttr(test.value)
+		if (attr.Bit(dexAttrShiny) != 0) != test.shiny || (attr.Bit(dexAttrVariant3) != 0) != test.epic {
+			t.Errorf("dexAttr(%v): expected shiny %v and epic %v, got %s", test.value, test.shiny, test.epic, attr)
+		}
+	}
+}
+
+func TestDexCounter(t *testing.T) {
+	counter := newDexCounter()
+	counter.add(defs.SystemSaveData{
+		DexData: defs.DexData{
+			1: {SeenAttr: float64(17), CaughtAttr: float64(2 | 32 | 128), HatchedCount: 1},
+			4: {SeenAttr: float64(17), CaughtAttr: float64(0)},
+		},
+		StarterData: defs.StarterData{
+			1: {ClassicWinCount: 2, PassiveAttr: 3},
+		},
+	})
+	counter.add(defs.SystemSaveData{
+		DexData: defs.DexData{
+			1: {SeenAttr: "17", CaughtAttr: "145"},
+		},
+	})
+
+	counts := counter.counts()
+	if counter.players != 2 || len(counts) != 2 {
+		t.Fatalf("expected 2 players and 2 species, got %d and %d", counter.players, len(counts))
+	}
+
+	expected := defs.DexSpeciesCounts{Species: 1, Seen: 2, Caught: 2, Hatched: 1, Shiny: 1, Variant2: 1, ClassicWins: 1, Passive: 1}
+	if counts[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, counts[0])
+	}
+
+	expected = defs.DexSpeciesCounts{Species: 4, Seen: 1}
+	if counts[1] != expected {
+		t.Errorf("expected %+v, got %+v", expected, counts[1])
+	}
+}
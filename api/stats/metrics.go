@@ -0,0 +1,191 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// SeriesMaxDays caps the number of days a single /game/stats request can
+// span.
+var SeriesMaxDays = 366
+
+// title holds the latest title stats. They are refreshed by the scheduler and
+// read by every title screen load.
+var title = struct {
+	mu    sync.RWMutex
+	stats defs.TitleStats
+}{}
+
+// Title returns the latest title stats.
+func Title() defs.TitleStats {
+	title.mu.RLock()
+	defer title.mu.RUnlock()
+
+	return title.stats
+}
+
+// Interface for database operations needed for refreshing the player count.
+type PlayerCountStore interface {
+	FetchPlayerCount() (int, error)
+}
+
+// RefreshPlayerCount refreshes the number of players online.
+func RefreshPlayerCount[T PlayerCountStore](store T) error {
+	playerCount, err := store.FetchPlayerCount()
+	if err != nil {
+		return fmt.Errorf("failed to fetch player count: %s", err)
+	}
+
+	title.mu.Lock()
+	title.stats.PlayerCount = playerCount
+	title.mu.Unlock()
+
+	return nil
+}
+
+// Interface for database operations needed for refreshing the totals.
+type TotalsStore interface {
+	FetchBattleCount() (int, error)
+	FetchClassicSessionCount() (int, error)
+	FetchAccountCount() (int, error)
+	FetchDailyRunPlayerCount(date string) (int, error)
+}
+
+// RefreshTotals refreshes the title stats that are summed over every account.
+// These are expensive to count, so they are refreshed less often than the
+// player count. date is the date of the current daily run.
+func RefreshTotals[T TotalsStore](store T, date string) error {
+	battleCount, err := store.FetchBattleCount()
+	if err != nil {
+		return fmt.Errorf("failed to fetch battle count: %s", err)
+	}
+
+	classicSessionCount, err := store.FetchClassicSessionCount()
+	if err != nil {
+		return fmt.Errorf("failed to fetch classic session count: %s", err)
+	}
+
+	accountCount, err := store.FetchAccountCount()
+	if err != nil {
+		return fmt.Errorf("failed to fetch account count: %s", err)
+	}
+
+	dailyRunPlayers, err := store.FetchDailyRunPlayerCount(date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch daily run player count: %s", err)
+	}
+
+	title.mu.Lock()
+	title.stats.BattleCount = battleCount
+	title.stats.ClassicSessionCount = classicSessionCount
+	title.stats.AccountCount = accountCount
+	title.stats.DailyRunPlayers = dailyRunPlayers
+	title.mu.Unlock()
+
+	return nil
+}
+
+// Interface for database operations needed for taking a stats snapshot.
+type SnapshotStore interface {
+	FetchRegistrationCount(date string) (int, error)
+	FetchDailyRunPlayerCount(date string) (int, error)
+	FetchWinCount(date string) (int, error)
+	StoreStatsSnapshot(snapshot defs.StatsSnapshot) error
+}
+
+// Snapshot records the metrics of the current UTC day, combining the latest
+// title stats with the day's registrations and wins and the players of the
+// daily run dated dailyDate, which follows the daily rollover rather than UTC.
+func Snapshot[T SnapshotStore](store T, now time.Time, dailyDate string) error {
+	date := now.UTC().Format(time.DateOnly)
+	current := Title()
+
+	registrations, err := store.FetchRegistrationCount(date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch registration count: %s", err)
+	}
+
+	dailyRunPlayers, err := store.FetchDailyRunPlayerCount(dailyDate)
+	if err != nil {
+		return fmt.Errorf("failed to fetch daily run player count: %s", err)
+	}
+
+	wins, err := store.FetchWinCount(date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch win count: %s", err)
+	}
+
+	return store.StoreStatsSnapshot(defs.StatsSnapshot{
+		Date:                date,
+		PeakPlayerCount:     current.PlayerCount,
+		BattleCount:         current.BattleCount,
+		ClassicSessionCount: current.ClassicSessionCount,
+		AccountCount:        current.AccountCount,
+		Registrations:       registrations,
+		DailyRunPlayers:     dailyRunPlayers,
+		Wins:                wins,
+	})
+}
+
+// Interface for database operations needed for fetching the stats series.
+type SeriesStore interface {
+	FetchStatsSnapshots(from, to string) ([]defs.StatsSnapshot, error)
+}
+
+// /game/stats - fetch the daily snapshots between from and to, inclusive.
+// to defaults to today and from to SeriesMaxDays before to.
+func Series[T SeriesStore](store T, from, to string) ([]defs.StatsSnapshot, error) {
+	end := time.Now().UTC()
+	if to != "" {
+		var err error
+		end, err = time.Parse(time.DateOnly, to)
+		if err != nil {
+			return nil, apierror.ErrInvalidDate.Withf("invalid to date")
+		}
+	}
+
+	start := end.AddDate(0, 0, -(SeriesMaxDays - 1))
+	if from != "" {
+		var err error
+		start, err = time.Parse(time.DateOnly, from)
+		if err != nil {
+			return nil, apierror.ErrInvalidDate.Withf("invalid from date")
+		}
+	}
+
+	if start.After(end) {
+		return nil, apierror.ErrInvalidParameter.Withf("from is after to")
+	}
+
+	if end.Sub(start) >= time.Duration(SeriesMaxDays)*24*time.Hour {
+		return nil, apierror.ErrInvalidParameter.Withf("range can span at most %d days", SeriesMaxDays)
+	}
+
+	snapshots, err := store.FetchStatsSnapshots(start.Format(time.DateOnly), end.Format(time.DateOnly))
+	if err != nil {
+		return nil, apierror.Internalf("failed to fetch stats snapshots: %s", err)
+	}
+
+	return snapshots, nil
+}
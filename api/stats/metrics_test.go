@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+type mockMetricsStore struct {
+	snapshot  defs.StatsSnapshot
+	from, to  string
+	dailyDate string
+}
+
+func (m *mockMetricsStore) FetchPlayerCount() (int, error)         { return 12, nil }
+func (m *mockMetricsStore) FetchBattleCount() (int, error)         { return 3400, nil }
+func (m *mockMetricsStore) FetchClassicSessionCount() (int, error) { return 560, nil }
+func (m *mockMetricsStore) FetchAccountCount() (int, error)        { return 78, nil }
+func (m *mockMetricsStore) FetchDailyRunPlayerCount(date string) (int, error) {
+	m.dailyDate = date
+	return 9, nil
+}
+func (m *mockMetricsStore) FetchRegistrationCount(date string) (int, error) { return 4, nil }
+func (m *mockMetricsStore) FetchWinCount(date string) (int, error)          { return 2, nil }
+func (m *mockMetricsStore) StoreStatsSnapshot(snapshot defs.StatsSnapshot) error {
+	m.snapshot = snapshot
+	return nil
+}
+func (m *mockMetricsStore) FetchStatsSnapshots(from, to string) ([]defs.StatsSnapshot, error) {
+	m.from, m.to = from, to
+	return []defs.StatsSnapshot{}, nil
+}
+
+func TestSnapshot(t *testing.T) {
+	store := &mockMetricsStore{}
+	if err := RefreshPlayerCount(store); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if err := RefreshTotals(store, "2025-03-01"); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	expected := defs.TitleStats{PlayerCount: 12, BattleCount: 3400, ClassicSessionCount: 560, AccountCount: 78, DailyRunPlayers: 9}
+	if Title() != expected {
+		t.Errorf("expected title stats %+v, got %+v", expected, Title())
+	}
+
+	// the daily run rolls over ahead of UTC
+	err := Snapshot(store, time.Date(2025, 3, 1, 23, 59, 0, 0, time.UTC), "2025-03-02")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	if store.dailyDate != "2025-03-02" {
+		t.Errorf("expected daily run players of 2025-03-02, got %s", store.dailyDate)
+	}
+
+	if store.snapshot.Date != "2025-03-01" || store.snapshot.PeakPlayerCount != 12 || store.snapshot.Registrations != 4 || store.snapshot.Wins != 2 {
+		t.Errorf("unexpected snapshot %+v", store.snapshot)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		from, to string
+		valid    bool
+	}{
+		{"2025-01-01", "2025-01-31", true},
+		{"", "2025-01-31", true},
+		{"2025-02-01", "2025-01-31", false},
+		{"2024-01-01", "2025-01-31", false},
+		{"yesterday", "", false},
+	}
+
+	for _, test := range tests {
+		store := &mockMetricsStore{}
+		_, err := Series(store, test.from, test.to)
+		if (err == nil) != test.valid {
+			t.Errorf("Series(%q, %q): expected valid %v, got error %v", test.from, test.to, test.valid, err)
+		}
+	}
+
+	if _, err := Series(&mockMetricsStore{}, "yesterday", ""); !errors.Is(err, apierror.ErrInvalidDate) {
+		t.Errorf("expected an invalid date error, got %v", err)
+	}
+
+	store := &mockMetricsStore{}
+	Series(store, "", "2025-01-31")
+	if store.from != "2024-02-01" || store.to != "2025-01-31" {
+		t.Errorf("expected default range 2024-02-01 to 2025-01-31, got %s to %s", store.from, store.to)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/db"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const (
+	RankingsDefaultPageSize = 10
+	RankingsMaxPageSize     = 50
+)
+
+var (
+	// RankingsRefresh is how long a stat leaderboard is served from the cache
+	// before being reloaded. Stats only move when players sync their system
+	// data, and bans and renames show up once the board is reloaded.
+	RankingsRefresh = 5 * time.Minute
+
+	// RankingsCacheSize is how many of the top places of each leaderboard are
+	// cached. Pages past them are read from the database.
+	RankingsCacheSize = 1000
+)
+
+// board holds the top places of a stat leaderboard and the number of
+// accounts ranked on it.
+type board struct {
+	rankings []defs.StatRanking
+	count    int
+	builtAt  time.Time
+}
+
+var boards = struct {
+	mu     sync.Mutex
+	byStat map[string]*board
+}{byStat: make(map[string]*board)}
+
+// Interface for database operations needed for fetching stat rankings.
+type RankingsStore interface {
+	FetchStatRankings(stat string, limit, offset int) ([]defs.StatRanking, error)
+	FetchStatRankingCount(stat string) (int, error)
+}
+
+// load returns the cached board for stat, reloading it if it is stale, along
+// with a version identifying its contents.
+func load[T RankingsStore](store T, stat string) (*board, string, error) {
+	boards.mu.Lock()
+	defer boards.mu.Unlock()
+
+	cached, ok := boards.byStat[stat]
+	if !ok || time.Since(cached.builtAt) >= RankingsRefresh {
+		rankings, err := store.FetchStatRankings(stat, RankingsCacheSize, 0)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch stat rankings: %s", err)
+		}
+
+		count, err := store.FetchStatRankingCount(stat)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch stat ranking count: %s", err)
+		}
+
+		cached = &board{rankings: rankings, count: count, builtAt: time.Now()}
+		boards.byStat[stat] = cached
+	}
+
+	return cached, fmt.Sprintf("%s-%x", stat, cached.builtAt.UnixNano()), nil
+}
+
+// validate checks the requested stat and page and returns the page size to
+// use.
+func validate(stat string, page, pageSize int) (int, error) {
+	if !slices.Contains(db.RankedStatColumns, stat) {
+		return 0, fmt.Errorf("unknown stat %q, expected one of %s", stat, strings.Join(db.RankedStatColumns, ", "))
+	}
+
+	if page < 1 {
+		return 0, fmt.Errorf("invalid page")
+	}
+
+	if pageSize == 0 {
+		return RankingsDefaultPageSize, nil
+	}
+
+	if pageSize < 1 || pageSize > RankingsMaxPageSize {
+		return 0, fmt.Errorf("page size must be between 1 and %d", RankingsMaxPageSize)
+	}
+
+	return pageSize, nil
+}
+
+// /stats/rankings - fetch a page of the all-time leaderboard of a stat.
+// version identifies the cached board the page came from.
+func Rankings[T RankingsStore](store T, stat string, page, pageSize int) ([]defs.StatRanking, string, error) {
+	pageSize, err := validate(stat, page, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cached, version, err := load(store, stat)
+	if err != nil {
+		return nil, "", err
+	}
+
+	from, to := (page-1)*pageSize, page*pageSize
+	if to <= len(cached.rankings) || len(cached.rankings) < RankingsCacheSize {
+		return cached.rankings[min(from, len(cached.rankings)):min(to, len(cached.rankings))], version, nil
+	}
+
+	rankings, err := store.FetchStatRankings(stat, pageSize, from)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rankings, "", nil
+}
+
+// /stats/rankingpagecount - fetch the page count of the all-time leaderboard
+// of a stat
+func RankingPageCount[T RankingsStore](store T, stat string, pageSize int) (int, string, error) {
+	pageSize, err := validate(stat, 1, pageSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	cached, version, err := load(store, stat)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return (cached.count + pageSize - 1) / pageSize, version, nil
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+type mockRankingsStore struct {
+	rankings []defs.StatRanking
+	queries  int
+}
+
+func (m *mockRankingsStore) FetchStatRankings(stat string, limit, offset int) ([]defs.StatRanking, error) {
+	m.queries++
+	return m.rankings[min(offset, len(m.rankings)):min(offset+limit, len(m.rankings))], nil
+}
+func (m *mockRankingsStore) FetchStatRankingCount(stat string) (int, error) {
+	return len(m.rankings), nil
+}
+
+func TestRankings(t *testing.T) {
+	defer func(size int) { RankingsCacheSize = size }(RankingsCacheSize)
+	RankingsCacheSize = 4
+
+	store := &mockRankingsStore{}
+	for i := range 10 {
+		store.rankings = append(store.rankings, defs.StatRanking{Rank: i + 1, Value: 100 - i})
+	}
+
+	rankings, version, err := Rankings(store, "pokemonCaught", 1, 3)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(rankings) != 3 || rankings[0].Rank != 1 || version == "" {
+		t.Errorf("unexpected first page: %+v (version %q)", rankings, version)
+	}
+
+	// the second page runs past the cached places, so it is read directly
+	queries := store.queries
+	rankings, version, err = Rankings(store, "pokemonCaught", 2, 3)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(rankings) != 3 || rankings[0].Rank != 4 || version != "" || store.queries != queries+1 {
+		t.Errorf("unexpected second page: %+v (version %q)", rankings, version)
+	}
+
+	count, _, err := RankingPageCount(store, "pokemonCaught", 3)
+	if err != nil || count != 4 {
+		t.Errorf("expected 4 pages, got %d (%v)", count, err)
+	}
+
+	_, _, err = Rankings(store, "goldenVouchers", 1, 3)
+	if err == nil {
+		t.Errorf("expected unranked stat to be rejected")
+	}
+}
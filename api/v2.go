@@ -0,0 +1,647 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package api
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/api/account"
+	"github.com/pagefaultgames/rogueserver/api/apierror"
+	"github.com/pagefaultgames/rogueserver/api/daily"
+	"github.com/pagefaultgames/rogueserver/api/savedata"
+	"github.com/pagefaultgames/rogueserver/api/stats"
+	"github.com/pagefaultgames/rogueserver/db"
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+/*
+	v2 handlers. Unlike v1, requests and responses are json throughout, the
+	resource is named by the path and the action by the method. Requests that
+	return nothing answer with 204. Handlers whose v1 counterpart already
+	fits are registered under both.
+*/
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func handleV2OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// queryInt reads an integer query parameter, or returns fallback if it is
+// not set.
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	if !r.URL.Query().Has(name) {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return 0, apierror.ErrInvalidParameter.Withf("failed to convert %s: %s", name, err)
+	}
+
+	return value, nil
+}
+
+// account
+
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type PasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type UsernameRequest struct {
+	Username string `json:"username"`
+}
+
+type ProfileSettingsRequest struct {
+	Private *bool `json:"private"`
+}
+
+type BanAppealRequest struct {
+	Appeal string `json:"appeal"`
+}
+
+func handleV2AccountRegister(w http.ResponseWriter, r *http.Request) {
+	var request CredentialsRequest
+	err := decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = account.Register(db.Store, request.Username, request.Password)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func handleV2AccountLogin(w http.ResponseWriter, r *http.Request) {
+	var request CredentialsRequest
+	err := decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	response, err := account.Login(db.Store, request.Username, request.Password)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+func handleV2AccountLogout(w http.ResponseWriter, r *http.Request) {
+	token, err := tokenFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = account.Logout(db.Store, token)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2AccountPassword(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var request PasswordRequest
+	err = decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = account.ChangePW(db.Store, uuid, request.Password)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	username, err := db.Store.FetchUsernameFromUUID(uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	// changing the password ends every session, so log the caller back in
+	response, err := account.Login(db.Store, username, request.Password)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+func handleV2AccountUsername(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var request UsernameRequest
+	err = decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = account.ChangeUsername(db.Store, uuid, request.Username)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	daily.InvalidateLeaderboards()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2AccountProfile(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var request ProfileSettingsRequest
+	err = decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if request.Private == nil {
+		httpError(w, r, apierror.ErrInvalidParameter.Withf("missing private"), http.StatusBadRequest)
+		return
+	}
+
+	err = account.SetProfilePrivate(db.Store, uuid, *request.Private)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2AccountBanAppeal(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var request BanAppealRequest
+	err = decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = account.AppealBan(db.Store, uuid, request.Appeal)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2AccountDelete(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var request PasswordRequest
+	err = decodeBody(w, r, &request)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	scheduled, err := account.RequestDeletion(db.Store, uuid, request.Password)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, r, AccountDeleteResponse{DeletionDate: scheduled.Format(time.DateTime)})
+}
+
+func handleV2AccountDeleteCancel(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = account.CancelDeletion(db.Store, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stats
+
+func handleV2StatRankings(w http.ResponseWriter, r *http.Request) {
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	pageSize, err := queryInt(r, "pageSize", 0)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	stat := r.URL.Query().Get("stat")
+
+	rankings, version, err := stats.Rankings(db.Store, stat, page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	pageCount, _, err := stats.RankingPageCount(db.Store, stat, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if notModified(w, r, version) {
+		return
+	}
+
+	writeJSON(w, r, defs.StatRankingsPage{Rankings: rankings, Page: page, PageCount: pageCount})
+}
+
+// saves
+
+// sessionSlotFromRequest authenticates a request for a session slot and makes
+// its client session the active one, as every session request does.
+func sessionSlotFromRequest(r *http.Request) ([]byte, int, error) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	slot, err := parseSlot(r.PathValue("slot"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if r.URL.Query().Get("clientSessionId") == "" {
+		return nil, 0, apierror.ErrMissingClientSessionId
+	}
+
+	err = db.Store.UpdateActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
+	if err != nil {
+		return nil, 0, apierror.Internalf("failed to update active session: %s", err)
+	}
+
+	return uuid, slot, nil
+}
+
+func handleV2SessionGet(w http.ResponseWriter, r *http.Request) {
+	uuid, slot, err := sessionSlotFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	save, err := savedata.GetSession(db.Store, uuid, slot)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, save)
+}
+
+func handleV2SessionPut(w http.ResponseWriter, r *http.Request) {
+	uuid, slot, err := sessionSlotFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var session defs.SessionSaveData
+	err = decodeBodyFor(w, r, "/v2/saves/sessions/{slot}", &session)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = saveSession(uuid, slot, session)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2SessionDelete(w http.ResponseWriter, r *http.Request) {
+	uuid, slot, err := sessionSlotFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = savedata.DeleteSession(db.Store, uuid, slot)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2SessionClear(w http.ResponseWriter, r *http.Request) {
+	uuid, slot, err := sessionSlotFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var session defs.SessionSaveData
+	err = decodeBodyFor(w, r, "/v2/saves/sessions/{slot}/clear", &session)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	response, err := clearSession(uuid, slot, session)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+func handleV2SessionNewClear(w http.ResponseWriter, r *http.Request) {
+	uuid, slot, err := sessionSlotFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	response, err := savedata.NewClear(db.Store, uuid, slot)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+func handleV2SystemGet(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	clientSessionId := r.URL.Query().Get("clientSessionId")
+	if clientSessionId == "" {
+		httpError(w, r, apierror.ErrMissingClientSessionId, http.StatusBadRequest)
+		return
+	}
+
+	err = db.Store.UpdateActiveSession(uuid, clientSessionId)
+	if err != nil {
+		httpError(w, r, apierror.Internalf("failed to update active session: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	save, err := savedata.GetSystem(db.Store, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, save)
+}
+
+func handleV2SystemPut(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = checkActiveSession(uuid, r.URL.Query().Get("clientSessionId"))
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	var system defs.SystemSaveData
+	err = decodeBody(w, r, &system)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = saveSystem(uuid, system)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2SystemDelete(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = savedata.DeleteSystem(db.Store, uuid)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleV2SystemVerify(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	clientSessionId := r.URL.Query().Get("clientSessionId")
+	if clientSessionId == "" {
+		httpError(w, r, apierror.ErrMissingClientSessionId, http.StatusBadRequest)
+		return
+	}
+
+	response, err := verifySystem(uuid, clientSessionId)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+func handleV2SavesPut(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuidFromRequest(r)
+	if err != nil {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	var data CombinedSaveData
+	err = decodeBody(w, r, &data)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = saveAll(uuid, data)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// daily
+
+func handleV2DailySeed(w http.ResponseWriter, r *http.Request) {
+	date := daily.Today()
+	seed, err := db.Store.GetDailyRunSeed(date)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, defs.DailySeed{Date: date, Seed: seed})
+}
+
+func handleV2DailyRankings(w http.ResponseWriter, r *http.Request) {
+	category, err := queryInt(r, "category", 0)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	pageSize, err := queryInt(r, "pageSize", 0)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	date, err := daily.RankingsDate(db.Store, r.URL.Query().Get("date"), r.URL.Query().Get("seed"))
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	rankings, version, err := daily.Rankings(db.Store, date, category, page, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	pageCount, _, err := daily.RankingPageCount(db.Store, date, category, pageSize)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(w, r, version) {
+		return
+	}
+
+	writeJSON(w, r, defs.DailyRankingsPage{Rankings: rankings, Page: page, PageCount: pageCount})
+}
+
+func handleV2DailyEventRankings(w http.ResponseWriter, r *http.Request) {
+	event, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httpError(w, r, apierror.ErrInvalidParameter.Withf("failed to convert id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	rankings, err := daily.EventRankings(db.Store, event, page)
+	if err != nil {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	pageCount, err := daily.EventRankingPageCount(db.Store, event)
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, defs.DailyRankingsPage{Rankings: rankings, Page: page, PageCount: pageCount})
+}
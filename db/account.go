@@ -22,6 +22,8 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/pagefaultgames/rogueserver/defs"
@@ -199,12 +201,13 @@ func (s *store) FetchLastLoggedInDateByUsername(username string) (string, error)
 }
 
 type AdminSearchResponse struct {
-	Username     string               `json:"username"`
-	DiscordId    string               `json:"discordId"`
-	GoogleId     string               `json:"googleId"`
-	LastActivity string               `json:"lastLoggedIn"` // TODO: this is currently lastLoggedIn to match server PR #54 with pokerogue PR #4198. We're hotfixing the server with this PR to return lastActivity, but we're not hotfixing the client, so are leaving this as lastLoggedIn so that it still talks to the client properly
-	Registered   string               `json:"registered"`
-	SystemData   *defs.SystemSaveData `json:"systemData,omitzero"`
+	Username        string                `json:"username"`
+	DiscordId       string                `json:"discordId"`
+	GoogleId        string                `json:"googleId"`
+	LastActivity    string                `json:"lastLoggedIn"` // TODO: this is currently lastLoggedIn to match server PR #54 with pokerogue PR #4198. We're hotfixing the server with this PR to return lastActivity, but we're not hotfixing the client, so are leaving this as lastLoggedIn so that it still talks to the client properly
+	Registered      string                `json:"registered"`
+	SystemData      *defs.SystemSaveData  `json:"systemData,omitzero"`
+	UsernameHistory []defs.UsernameChange `json:"usernameHistory,omitempty"`
 }
 
 func (s *store) FetchAdminDetailsByUsername(dbUsername string) (AdminSearchResponse, error) {
@@ -245,9 +248,15 @@ func (s *store) UpdateAccountLastActivity(uuid []byte) error {
 	return nil
 }
 
-func (s *store) UpdateAccountStats(uuid []byte, stats defs.GameStats, voucherCounts map[string]int) error {
-	var columns = []string{"playTime", "battles", "classicSessionsPlayed", "sessionsWon", "highestEndlessWave", "highestLevel", "pokemonSeen", "pokemonDefeated", "pokemonCaught", "pokemonHatched", "eggsPulled", "regularVouchers", "plusVouchers", "premiumVouchers", "goldenVouchers"}
+// AccountStatColumns are the columns of accountStats, in table order.
+var AccountStatColumns = []string{"playTime", "battles", "classicSessionsPlayed", "sessionsWon", "highestEndlessWave", "highestLevel", "pokemonSeen", "pokemonDefeated", "pokemonCaught", "pokemonHatched", "eggsPulled", "regularVouchers", "plusVouchers", "premiumVouchers", "goldenVouchers"}
+
+// RankedStatColumns are the AccountStatColumns with an all-time leaderboard.
+// Column names are spliced into the ranking queries, so only these are
+// accepted.
+var RankedStatColumns = []string{"highestEndlessWave", "sessionsWon", "pokemonSeen", "pokemonCaught", "pokemonHatched"}
 
+func (s *store) UpdateAccountStats(uuid []byte, stats defs.GameStats, voucherCounts map[string]int) error {
 	var statCols []string
 	var statValues []interface{}
 
@@ -262,7 +271,7 @@ func (s *store) UpdateAccountStats(uuid []byte, stats defs.GameStats, voucherCou
 			return fmt.Errorf("expected float64, got %T", v)
 		}
 
-		if slices.Contains(columns, k) {
+		if slices.Contains(AccountStatColumns, k) {
 			statCols = append(statCols, k)
 			statValues = append(statValues, value)
 		}
@@ -322,15 +331,6 @@ func (s *store) UpdateAccountStats(uuid []byte, stats defs.GameStats, voucherCou
 	return nil
 }
 
-func (s *store) SetAccountBanned(uuid []byte, banned bool) error {
-	_, err := handle.Exec("UPDATE accounts SET banned = ? WHERE uuid = ?", banned, uuid)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (s *store) AddAccountRecord(uuid []byte, username string, key, salt []byte) error {
 	_, err := handle.Exec("INSERT INTO accounts (uuid, username, hash, salt, registered) VALUES (?, ?, ?, ?, UTC_TIMESTAMP())", uuid, username, key, salt)
 	if err != nil {
@@ -487,3 +487,183 @@ func (s *store) RemoveGoogleIdByDiscordId(discordId string) error {
 
 	return nil
 }
+
+func (s *store) FetchAccountStats(uuid []byte) (map[string]int, error) {
+	stats := make(map[string]int, len(AccountStatColumns))
+
+	values := make([]any, len(AccountStatColumns))
+	for i := range values {
+		values[i] = new(int)
+	}
+
+	err := handle.QueryRow("SELECT "+strings.Join(AccountStatColumns, ", ")+" FROM accountStats WHERE uuid = ?", uuid).Scan(values...)
+	if err != nil {
+		return stats, err
+	}
+
+	for i, column := range AccountStatColumns {
+		stats[column] = *values[i].(*int)
+	}
+
+	return stats, nil
+}
+
+func (s *store) FetchAccountDetails(uuid []byte) (defs.AccountDetails, error) {
+	var details defs.AccountDetails
+	err := handle.QueryRow("SELECT username, registered, COALESCE(lastLoggedIn, ''), COALESCE(lastActivity, ''), banned, trainerId, secretId, COALESCE(discordId, ''), COALESCE(googleId, ''), profilePrivate FROM accounts WHERE uuid = ?", uuid).Scan(&details.Username, &details.Registered, &details.LastLoggedIn, &details.LastActivity, &details.Banned, &details.TrainerId, &details.SecretId, &details.DiscordId, &details.GoogleId, &details.Private)
+	if err != nil {
+		return details, err
+	}
+
+	return details, nil
+}
+
+func (s *store) UpdateAccountProfilePrivate(uuid []byte, private bool) error {
+	_, err := handle.Exec("UPDATE accounts SET profilePrivate = ? WHERE uuid = ?", private, uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAccount removes the account row. Every table holding account data
+// references accounts with ON DELETE CASCADE, so this removes all of it.
+func (s *store) DeleteAccount(uuid []byte) error {
+	_, err := handle.Exec("DELETE FROM accounts WHERE uuid = ?", uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) ScheduleAccountDeletion(uuid []byte, scheduled time.Time) error {
+	_, err := handle.Exec("INSERT INTO accountDeletions (uuid, requested, scheduled) VALUES (?, UTC_TIMESTAMP(), ?) ON DUPLICATE KEY UPDATE scheduled = LEAST(scheduled, VALUES(scheduled))", uuid, scheduled)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) CancelAccountDeletion(uuid []byte) (bool, error) {
+	result, err := handle.Exec("DELETE FROM accountDeletions WHERE uuid = ?", uuid)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// FetchAccountDeletion returns when the account is scheduled to be deleted,
+// or an empty string if it isn't.
+func (s *store) FetchAccountDeletion(uuid []byte) (string, error) {
+	var scheduled string
+	err := handle.QueryRow("SELECT scheduled FROM accountDeletions WHERE uuid = ?", uuid).Scan(&scheduled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return scheduled, nil
+}
+
+func (s *store) FetchDueAccountDeletions() ([][]byte, error) {
+	var uuids [][]byte
+
+	results, err := handle.Query("SELECT uuid FROM accountDeletions WHERE scheduled <= UTC_TIMESTAMP()")
+	if err != nil {
+		return uuids, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var uuid []byte
+		err = results.Scan(&uuid)
+		if err != nil {
+			return uuids, err
+		}
+
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids, results.Err()
+}
+
+func (s *store) UpdateAccountUsername(uuid []byte, username string) error {
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO usernameHistory (uuid, oldUsername, newUsername, changed) SELECT uuid, username, ?, UTC_TIMESTAMP() FROM accounts WHERE uuid = ?", username, uuid)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET username = ? WHERE uuid = ?", username, uuid)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FetchLastUsernameChange returns when the account last changed username
+// other than by capitalisation, or an empty string if it never has.
+func (s *store) FetchLastUsernameChange(uuid []byte) (string, error) {
+	var changed sql.NullString
+	err := handle.QueryRow("SELECT MAX(changed) FROM usernameHistory WHERE uuid = ? AND LOWER(oldUsername) != LOWER(newUsername)", uuid).Scan(&changed)
+	if err != nil {
+		return "", err
+	}
+
+	return changed.String, nil
+}
+
+func (s *store) FetchUsernameHistory(uuid []byte) ([]defs.UsernameChange, error) {
+	history := []defs.UsernameChange{}
+
+	results, err := handle.Query("SELECT oldUsername, newUsername, changed FROM usernameHistory WHERE uuid = ? ORDER BY changed DESC, id DESC", uuid)
+	if err != nil {
+		return history, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var change defs.UsernameChange
+		err = results.Scan(&change.OldUsername, &change.NewUsername, &change.Changed)
+		if err != nil {
+			return history, err
+		}
+
+		history = append(history, change)
+	}
+
+	return history, results.Err()
+}
+
+// FetchUsernameByPreviousUsername returns the current username of the account
+// that most recently gave up the given username.
+func (s *store) FetchUsernameByPreviousUsername(previous string) (string, error) {
+	var username string
+	err := handle.QueryRow("SELECT a.username FROM usernameHistory h JOIN accounts a ON a.uuid = h.uuid WHERE h.oldUsername = ? ORDER BY h.changed DESC, h.id DESC LIMIT 1", previous).Scan(&username)
+	if err != nil {
+		return "", err
+	}
+
+	return username, nil
+}
@@ -0,0 +1,96 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// AddAuditLogEntry appends to the admin audit log. The log is append-only;
+// there are deliberately no methods to update or delete entries.
+func (s *store) AddAuditLogEntry(actor []byte, action defs.AuditAction, target []byte, oldValue, newValue, ip string) error {
+	_, err := handle.Exec("INSERT INTO adminAuditLog (actor, actorUsername, action, target, targetUsername, oldValue, newValue, ip, timestamp) SELECT ?, (SELECT username FROM accounts WHERE uuid = ?), ?, ?, (SELECT username FROM accounts WHERE uuid = ?), ?, ?, ?, UTC_TIMESTAMP()", actor, actor, action, target, target, oldValue, newValue, ip)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) FetchAuditLog(filter defs.AuditLogFilter) (defs.AuditLogPage, error) {
+	page := defs.AuditLogPage{Entries: []defs.AuditLogEntry{}, Page: filter.Page}
+
+	var conditions []string
+	var args []any
+	if filter.Actor != nil {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Target != nil {
+		conditions = append(conditions, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "timestamp < DATE_ADD(?, INTERVAL 1 DAY)")
+		args = append(args, filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM adminAuditLog"+where, args...).Scan(&count)
+	if err != nil {
+		return page, err
+	}
+
+	page.PageCount = int(math.Ceil(float64(count) / float64(filter.PageSize)))
+
+	offset := (filter.Page - 1) * filter.PageSize
+	results, err := handle.Query("SELECT id, COALESCE(actorUsername, ''), action, COALESCE(targetUsername, ''), oldValue, newValue, ip, timestamp FROM adminAuditLog"+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, filter.PageSize, offset)...)
+	if err != nil {
+		return page, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var entry defs.AuditLogEntry
+		err = results.Scan(&entry.Id, &entry.Actor, &entry.Action, &entry.Target, &entry.Before, &entry.After, &entry.IP, &entry.Timestamp)
+		if err != nil {
+			return page, err
+		}
+
+		page.Entries = append(page.Entries, entry)
+	}
+
+	return page, results.Err()
+}
@@ -0,0 +1,216 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"math"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const activeBanCondition = "b.lifted IS NULL AND (b.expires IS NULL OR b.expires > UTC_TIMESTAMP())"
+
+const banColumns = "b.id, a.username, b.reason, COALESCE(i.username, ''), b.issued, COALESCE(b.expires, ''), b.evidence, COALESCE(b.lifted, ''), COALESCE(l.username, ''), b.liftReason, COALESCE(b.appeal, ''), COALESCE(b.appealed, '')"
+
+const banJoins = "accountBans b JOIN accounts a ON a.uuid = b.uuid LEFT JOIN accounts i ON i.uuid = b.issuer LEFT JOIN accounts l ON l.uuid = b.liftedBy"
+
+// AddAccountBan records a ban and marks the account as banned. issuer is nil
+// for bans issued automatically by the server, expires is nil for permanent bans.
+func (s *store) AddAccountBan(uuid, issuer []byte, reason, evidence string, expires *time.Time) error {
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO accountBans (uuid, issuer, reason, evidence, issued, expires) VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), ?)", uuid, issuer, reason, evidence, expires)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET banned = 1 WHERE uuid = ?", uuid)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LiftAccountBans lifts every active ban on the account and clears its banned flag.
+func (s *store) LiftAccountBans(uuid, liftedBy []byte, reason string) error {
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE accountBans b SET b.lifted = UTC_TIMESTAMP(), b.liftedBy = ?, b.liftReason = ? WHERE b.uuid = ? AND "+activeBanCondition, liftedBy, reason, uuid)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET banned = 0 WHERE uuid = ?", uuid)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClearLegacyBan clears the banned flag of an account that has no active ban
+// recorded, as is the case for accounts banned before bans were recorded. It
+// reports whether the flag was set.
+func (s *store) ClearLegacyBan(uuid []byte) (bool, error) {
+	result, err := handle.Exec("UPDATE accounts a SET a.banned = 0 WHERE a.uuid = ? AND a.banned = 1 AND NOT EXISTS (SELECT 1 FROM accountBans b WHERE b.uuid = a.uuid AND "+activeBanCondition+")", uuid)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// LiftExpiredBans lifts bans whose expiry has passed and unbans the affected
+// accounts, unless they are still serving another ban. It returns the
+// accounts that were unbanned.
+func (s *store) LiftExpiredBans() ([][]byte, error) {
+	var unbanned [][]byte
+
+	tx, err := handle.Begin()
+	if err != nil {
+		return unbanned, err
+	}
+
+	results, err := tx.Query("SELECT DISTINCT uuid FROM accountBans WHERE lifted IS NULL AND expires <= UTC_TIMESTAMP()")
+	if err != nil {
+		tx.Rollback()
+		return unbanned, err
+	}
+
+	var expired [][]byte
+	for results.Next() {
+		var uuid []byte
+		err = results.Scan(&uuid)
+		if err != nil {
+			results.Close()
+			tx.Rollback()
+			return unbanned, err
+		}
+
+		expired = append(expired, uuid)
+	}
+	results.Close()
+
+	for _, uuid := range expired {
+		_, err = tx.Exec("UPDATE accountBans SET lifted = expires, liftReason = 'expired' WHERE uuid = ? AND lifted IS NULL AND expires <= UTC_TIMESTAMP()", uuid)
+		if err != nil {
+			tx.Rollback()
+			return unbanned, err
+		}
+
+		result, err := tx.Exec("UPDATE accounts a SET a.banned = 0 WHERE a.uuid = ? AND NOT EXISTS (SELECT 1 FROM accountBans b WHERE b.uuid = a.uuid AND "+activeBanCondition+")", uuid)
+		if err != nil {
+			tx.Rollback()
+			return unbanned, err
+		}
+
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			unbanned = append(unbanned, uuid)
+		}
+	}
+
+	return unbanned, tx.Commit()
+}
+
+// FetchActiveBan returns the most recent active ban on the account, or
+// sql.ErrNoRows if it is not banned.
+func (s *store) FetchActiveBan(uuid []byte) (defs.Ban, error) {
+	var ban defs.Ban
+	err := handle.QueryRow("SELECT "+banColumns+" FROM "+banJoins+" WHERE b.uuid = ? AND "+activeBanCondition+" ORDER BY b.id DESC LIMIT 1", uuid).Scan(&ban.Id, &ban.Username, &ban.Reason, &ban.Issuer, &ban.Issued, &ban.Expires, &ban.Evidence, &ban.Lifted, &ban.LiftedBy, &ban.LiftReason, &ban.Appeal, &ban.Appealed)
+	if err != nil {
+		return ban, err
+	}
+
+	return ban, nil
+}
+
+// FetchBans returns a page of bans, newest first. target limits the result
+// to a single account when not nil.
+func (s *store) FetchBans(target []byte, activeOnly bool, page, pageSize int) (defs.BanPage, error) {
+	response := defs.BanPage{Bans: []defs.Ban{}, Page: page}
+
+	where := " WHERE 1 = 1"
+	var args []any
+	if target != nil {
+		where += " AND b.uuid = ?"
+		args = append(args, target)
+	}
+	if activeOnly {
+		where += " AND " + activeBanCondition
+	}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accountBans b"+where, args...).Scan(&count)
+	if err != nil {
+		return response, err
+	}
+
+	response.PageCount = int(math.Ceil(float64(count) / float64(pageSize)))
+
+	results, err := handle.Query("SELECT "+banColumns+" FROM "+banJoins+where+" ORDER BY b.id DESC LIMIT ? OFFSET ?", append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return response, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var ban defs.Ban
+		err = results.Scan(&ban.Id, &ban.Username, &ban.Reason, &ban.Issuer, &ban.Issued, &ban.Expires, &ban.Evidence, &ban.Lifted, &ban.LiftedBy, &ban.LiftReason, &ban.Appeal, &ban.Appealed)
+		if err != nil {
+			return response, err
+		}
+
+		response.Bans = append(response.Bans, ban)
+	}
+
+	return response, results.Err()
+}
+
+// AddBanAppeal attaches an appeal to the account's active ban. Each ban can
+// only be appealed once; it reports false if there was nothing to appeal.
+func (s *store) AddBanAppeal(uuid []byte, appeal string) (bool, error) {
+	result, err := handle.Exec("UPDATE accountBans b SET b.appeal = ?, b.appealed = UTC_TIMESTAMP() WHERE b.uuid = ? AND b.appeal IS NULL AND "+activeBanCondition, appeal, uuid)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
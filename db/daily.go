@@ -18,14 +18,15 @@
 package db
 
 import (
+	"database/sql"
 	"math"
 
 	"github.com/pagefaultgames/rogueserver/defs"
 )
 
-func (s *store) TryAddDailyRun(seed string) (string, error) {
+func (s *store) TryAddDailyRun(date, seed string) (string, error) {
 	var actualSeed string
-	err := handle.QueryRow("INSERT INTO dailyRuns (seed, date) VALUES (?, UTC_DATE()) ON DUPLICATE KEY UPDATE date = date RETURNING seed", seed).Scan(&actualSeed)
+	err := handle.QueryRow("INSERT INTO dailyRuns (seed, date) VALUES (?, ?) ON DUPLICATE KEY UPDATE date = date RETURNING seed", seed, date).Scan(&actualSeed)
 	if err != nil {
 		return "", err
 	}
@@ -33,9 +34,9 @@ func (s *store) TryAddDailyRun(seed string) (string, error) {
 	return actualSeed, nil
 }
 
-func (s *store) GetDailyRunSeed() (string, error) {
+func (s *store) GetDailyRunSeed(date string) (string, error) {
 	var seed string
-	err := handle.QueryRow("SELECT seed FROM dailyRuns WHERE date = UTC_DATE()").Scan(&seed)
+	err := handle.QueryRow("SELECT seed FROM dailyRuns WHERE date = ?", date).Scan(&seed)
 	if err != nil {
 		return "", err
 	}
@@ -43,8 +44,11 @@ func (s *store) GetDailyRunSeed() (string, error) {
 	return seed, nil
 }
 
-func (s *store) AddOrUpdateAccountDailyRun(uuid []byte, score int, wave int) error {
-	_, err := handle.Exec("INSERT INTO accountDailyRuns (uuid, date, score, wave, timestamp) VALUES (?, UTC_DATE(), ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE score = GREATEST(score, ?), wave = GREATEST(wave, ?), timestamp = IF(score < ?, UTC_TIMESTAMP(), timestamp)", uuid, score, wave, score, wave, score)
+// AddOrUpdateAccountDailyRun keeps the account's best score and wave of the day.
+// The timestamp is assigned first: assignments run in order and it has to
+// compare against the old score.
+func (s *store) AddOrUpdateAccountDailyRun(uuid []byte, date string, score int, wave int) error {
+	_, err := handle.Exec("INSERT INTO accountDailyRuns (uuid, date, score, wave, timestamp) VALUES (?, ?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE timestamp = IF(score < ?, UTC_TIMESTAMP(), timestamp), score = GREATEST(score, ?), wave = GREATEST(wave, ?)", uuid, date, score, wave, score, score, wave)
 	if err != nil {
 		return err
 	}
@@ -52,20 +56,14 @@ func (s *store) AddOrUpdateAccountDailyRun(uuid []byte, score int, wave int) err
 	return nil
 }
 
-func (s *store) FetchRankings(category int, page int) ([]defs.DailyRanking, error) {
+// FetchRankings ranks the daily runs between from and to inclusive. The daily
+// category ranks single runs, the others rank the sum of each account's runs.
+func (s *store) FetchRankings(category int, from, to string, page, pageSize int) ([]defs.DailyRanking, error) {
 	var rankings []defs.DailyRanking
 
-	offset := (page - 1) * 10
+	offset := (page - 1) * pageSize
 
-	var query string
-	switch category {
-	case 0:
-		query = "SELECT RANK() OVER (ORDER BY adr.score DESC, adr.timestamp), a.username, adr.score, adr.wave FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date JOIN accounts a ON adr.uuid = a.uuid WHERE dr.date = UTC_DATE() AND a.banned = 0 LIMIT 10 OFFSET ?"
-	case 1:
-		query = "SELECT RANK() OVER (ORDER BY SUM(adr.score) DESC, adr.timestamp), a.username, SUM(adr.score), 0 FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date JOIN accounts a ON adr.uuid = a.uuid WHERE dr.date >= DATE_SUB(DATE(UTC_TIMESTAMP()), INTERVAL DAYOFWEEK(UTC_TIMESTAMP()) - 1 DAY) AND a.banned = 0 GROUP BY a.username ORDER BY 1 LIMIT 10 OFFSET ?"
-	}
-
-	results, err := handle.Query(query, offset)
+	results, err := handle.Query("SELECT ranking, username, score, wave FROM ("+rankingsQuery(category)+") r ORDER BY position LIMIT ? OFFSET ?", from, to, pageSize, offset)
 	if err != nil {
 		return rankings, err
 	}
@@ -85,20 +83,328 @@ func (s *store) FetchRankings(category int, page int) ([]defs.DailyRanking, erro
 	return rankings, nil
 }
 
-func (s *store) FetchRankingPageCount(category int) (int, error) {
+// rankingsQuery returns a query ranking the daily runs between two dates, to
+// be bound in that order. The daily category ranks single runs, the others
+// rank the sum of each account's runs. Equal scores are ordered by who got
+// there first: a run's timestamp is when its best score was set, so for a sum
+// that is the latest timestamp of its runs. position breaks ties in rank.
+func rankingsQuery(category int) string {
+	if category == defs.RankingCategoryDaily {
+		return "SELECT adr.uuid, a.username, adr.score, adr.wave, RANK() OVER (ORDER BY adr.score DESC, adr.timestamp) AS ranking, ROW_NUMBER() OVER (ORDER BY adr.score DESC, adr.timestamp, adr.uuid) AS position FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun
+	}
+
+	return "SELECT adr.uuid, a.username, SUM(adr.score) AS score, 0 AS wave, RANK() OVER (ORDER BY SUM(adr.score) DESC, MAX(adr.timestamp)) AS ranking, ROW_NUMBER() OVER (ORDER BY SUM(adr.score) DESC, MAX(adr.timestamp), adr.uuid) AS position FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun + " GROUP BY adr.uuid, a.username"
+}
+
+// FetchLeaderboard returns the unranked entries of the category between from
+// and to inclusive, in the same shape as rankingsQuery.
+func (s *store) FetchLeaderboard(category int, from, to string) ([]*defs.LeaderboardEntry, error) {
+	var entries []*defs.LeaderboardEntry
+
+	query := "SELECT adr.uuid, a.username, adr.score, adr.wave, adr.timestamp FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun
+	if category != defs.RankingCategoryDaily {
+		query = "SELECT adr.uuid, a.username, SUM(adr.score), 0, MAX(adr.timestamp) FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun + " GROUP BY adr.uuid, a.username"
+	}
+
+	results, err := handle.Query(query, from, to)
+	if err != nil {
+		return entries, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		entry := new(defs.LeaderboardEntry)
+		err = results.Scan(&entry.Uuid, &entry.Username, &entry.Score, &entry.Wave, &entry.Timestamp)
+		if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, results.Err()
+}
+
+// FetchPlayerRanking returns the account's ranking along with up to
+// neighbors entries on either side of it.
+func (s *store) FetchPlayerRanking(category int, from, to string, uuid []byte, neighbors int) (defs.PlayerRanking, error) {
+	ranking := defs.PlayerRanking{Rankings: []defs.DailyRanking{}}
+
+	results, err := handle.Query("WITH ranked AS ("+rankingsQuery(category)+") SELECT r.ranking, r.username, r.score, r.wave, r.uuid = me.uuid FROM ranked r JOIN ranked me ON me.uuid = ? WHERE r.position BETWEEN me.position - ? AND me.position + ? ORDER BY r.position", from, to, uuid, neighbors, neighbors)
+	if err != nil {
+		return ranking, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var entry defs.DailyRanking
+		err = results.Scan(&entry.Rank, &entry.Username, &entry.Score, &entry.Wave, &entry.Self)
+		if err != nil {
+			return ranking, err
+		}
+
+		if entry.Self {
+			ranking.Rank, ranking.Score, ranking.Wave = entry.Rank, entry.Score, entry.Wave
+		}
+
+		ranking.Rankings = append(ranking.Rankings, entry)
+	}
+
+	err = results.Err()
+	if err != nil {
+		return ranking, err
+	}
+
+	if len(ranking.Rankings) == 0 {
+		return ranking, sql.ErrNoRows
+	}
+
+	return ranking, nil
+}
+
+func (s *store) FetchRankingPageCount(category int, from, to string, pageSize int) (int, error) {
+	// banned accounts and runs under review are left out, matching the pages
+	// from FetchRankings
 	var query string
 	switch category {
-	case 0:
-		query = "SELECT COUNT(a.username) FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date JOIN accounts a ON adr.uuid = a.uuid WHERE dr.date = UTC_DATE()"
-	case 1:
-		query = "SELECT COUNT(DISTINCT a.username) FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date JOIN accounts a ON adr.uuid = a.uuid WHERE dr.date >= DATE_SUB(DATE(UTC_TIMESTAMP()), INTERVAL DAYOFWEEK(UTC_TIMESTAMP()) - 1 DAY)"
+	case defs.RankingCategoryDaily:
+		query = "SELECT COUNT(*) FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun
+	default:
+		query = "SELECT COUNT(DISTINCT adr.uuid) FROM accountDailyRuns adr JOIN accounts a ON adr.uuid = a.uuid WHERE adr.date BETWEEN ? AND ? AND a.banned = 0 AND " + unreviewedDailyRun
 	}
 
 	var recordCount int
-	err := handle.QueryRow(query).Scan(&recordCount)
+	err := handle.QueryRow(query, from, to).Scan(&recordCount)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(math.Ceil(float64(recordCount) / float64(pageSize))), nil
+}
+
+func (s *store) FetchDailyRunDate(seed string) (string, error) {
+	var date string
+	err := handle.QueryRow("SELECT date FROM dailyRuns WHERE seed = ? ORDER BY date DESC LIMIT 1", seed).Scan(&date)
+	if err != nil {
+		return "", err
+	}
+
+	return date, nil
+}
+
+const (
+	historyRuns   = "FROM accountDailyRuns adr JOIN accounts a ON a.uuid = adr.uuid WHERE adr.date = dr.date AND a.banned = 0 AND " + unreviewedDailyRun
+	historyWinner = "ORDER BY adr.score DESC, adr.timestamp LIMIT 1"
+)
+
+// FetchDailyHistory lists the daily runs up to and including to, newest
+// first, with their participant count and winner.
+func (s *store) FetchDailyHistory(to string, page, pageSize int) (defs.DailyHistoryPage, error) {
+	history := defs.DailyHistoryPage{Runs: []defs.DailyHistoryEntry{}, Page: page}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM dailyRuns WHERE date <= ?", to).Scan(&count)
+	if err != nil {
+		return history, err
+	}
+
+	history.PageCount = int(math.Ceil(float64(count) / float64(pageSize)))
+
+	results, err := handle.Query("SELECT dr.date, dr.seed, (SELECT COUNT(*) "+historyRuns+"), COALESCE((SELECT a.username "+historyRuns+" "+historyWinner+"), ''), COALESCE((SELECT adr.score "+historyRuns+" "+historyWinner+"), 0) FROM dailyRuns dr WHERE dr.date <= ? ORDER BY dr.date DESC LIMIT ? OFFSET ?", to, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return history, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var entry defs.DailyHistoryEntry
+		err = results.Scan(&entry.Date, &entry.Seed, &entry.Participants, &entry.Winner, &entry.WinnerScore)
+		if err != nil {
+			return history, err
+		}
+
+		history.Runs = append(history.Runs, entry)
+	}
+
+	return history, results.Err()
+}
+
+func (s *store) FetchAccountDailyRuns(uuid []byte) ([]defs.AccountDailyRun, error) {
+	var runs []defs.AccountDailyRun
+
+	results, err := handle.Query("SELECT adr.date, dr.seed, adr.score, adr.wave, adr.timestamp FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date WHERE adr.uuid = ? ORDER BY adr.date", uuid)
+	if err != nil {
+		return runs, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var run defs.AccountDailyRun
+		err = results.Scan(&run.Date, &run.Seed, &run.Score, &run.Wave, &run.Timestamp)
+		if err != nil {
+			return runs, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, results.Err()
+}
+
+// FetchAccountDailySummary counts the account's daily runs and the ones it
+// topped, and returns its best run, or nil if it has none. Runs under review
+// are left out, as they are on the leaderboards.
+func (s *store) FetchAccountDailySummary(uuid []byte) (int, int, *defs.AccountDailyRun, error) {
+	var runs, wins int
+	err := handle.QueryRow("SELECT COUNT(*), COALESCE(SUM(NOT EXISTS (SELECT 1 FROM accountDailyRuns o JOIN accounts a ON a.uuid = o.uuid WHERE o.date = adr.date AND a.banned = 0 AND (o.score > adr.score OR (o.score = adr.score AND o.timestamp < adr.timestamp)) AND NOT EXISTS (SELECT 1 FROM flaggedRuns f WHERE f.uuid = o.uuid AND f.date = o.date AND f.status <> 'approved'))), 0) FROM accountDailyRuns adr WHERE adr.uuid = ? AND "+unreviewedDailyRun, uuid).Scan(&runs, &wins)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if runs == 0 {
+		return 0, 0, nil, nil
+	}
+
+	best := new(defs.AccountDailyRun)
+	err = handle.QueryRow("SELECT adr.date, dr.seed, adr.score, adr.wave, adr.timestamp FROM accountDailyRuns adr JOIN dailyRuns dr ON dr.date = adr.date WHERE adr.uuid = ? AND "+unreviewedDailyRun+" ORDER BY adr.score DESC, adr.timestamp LIMIT 1", uuid).Scan(&best.Date, &best.Seed, &best.Score, &best.Wave, &best.Timestamp)
+	if err != nil {
+		return runs, wins, nil, err
+	}
+
+	return runs, wins, best, nil
+}
+
+func (s *store) FetchDailyRunOverride(date string) (string, error) {
+	var seed string
+	err := handle.QueryRow("SELECT seed FROM dailyRunOverrides WHERE date = ?", date).Scan(&seed)
+	if err != nil {
+		return "", err
+	}
+
+	return seed, nil
+}
+
+func (s *store) AddDailyRunOverride(date, seed, name string, createdBy []byte) error {
+	_, err := handle.Exec("INSERT INTO dailyRunOverrides (date, seed, name, createdBy, created) VALUES (?, ?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE seed = VALUES(seed), name = VALUES(name), createdBy = VALUES(createdBy), created = VALUES(created)", date, seed, name, createdBy)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) RemoveDailyRunOverride(date string) (bool, error) {
+	result, err := handle.Exec("DELETE FROM dailyRunOverrides WHERE date = ?", date)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// FetchDailyRuns returns the recorded daily runs between from and to inclusive,
+// newest first.
+func (s *store) FetchDailyRuns(from, to string) ([]defs.DailyScheduleEntry, error) {
+	runs := []defs.DailyScheduleEntry{}
+
+	results, err := handle.Query("SELECT dr.date, dr.seed, COALESCE(o.name, ''), o.date IS NOT NULL FROM dailyRuns dr LEFT JOIN dailyRunOverrides o ON o.date = dr.date WHERE dr.date BETWEEN ? AND ? ORDER BY dr.date DESC", from, to)
+	if err != nil {
+		return runs, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var run defs.DailyScheduleEntry
+		err = results.Scan(&run.Date, &run.Seed, &run.Name, &run.Override)
+		if err != nil {
+			return runs, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, results.Err()
+}
+
+// FetchDailyRunOverrides returns the overrides scheduled between from and to
+// inclusive. Seeds are left out, as these runs may not have started yet.
+func (s *store) FetchDailyRunOverrides(from, to string) ([]defs.DailyScheduleEntry, error) {
+	overrides := []defs.DailyScheduleEntry{}
+
+	results, err := handle.Query("SELECT date, name FROM dailyRunOverrides WHERE date BETWEEN ? AND ? ORDER BY date", from, to)
+	if err != nil {
+		return overrides, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		override := defs.DailyScheduleEntry{Override: true}
+		err = results.Scan(&override.Date, &override.Name)
+		if err != nil {
+			return overrides, err
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, results.Err()
+}
+
+func (s *store) FetchDailySeedSecrets() ([]defs.DailySeedSecret, error) {
+	var secrets []defs.DailySeedSecret
+
+	results, err := handle.Query("SELECT version, activeFrom, secret FROM dailySeedSecrets ORDER BY activeFrom, version")
+	if err != nil {
+		return secrets, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var secret defs.DailySeedSecret
+		err = results.Scan(&secret.Version, &secret.ActiveFrom, &secret.Secret)
+		if err != nil {
+			return secrets, err
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, results.Err()
+}
+
+// TryAddInitialDailySeedSecret adds the first seed secret, unless another
+// instance got there first.
+func (s *store) TryAddInitialDailySeedSecret(secret []byte) error {
+	_, err := handle.Exec("INSERT IGNORE INTO dailySeedSecrets (version, secret, activeFrom, created) VALUES (1, ?, '1970-01-01', UTC_TIMESTAMP())", secret)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) AddDailySeedSecret(secret []byte, activeFrom string) (int, error) {
+	result, err := handle.Exec("INSERT INTO dailySeedSecrets (secret, activeFrom, created) VALUES (?, ?, UTC_TIMESTAMP())", secret, activeFrom)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 
-	return int(math.Ceil(float64(recordCount) / 10)), nil
+	return int(version), nil
 }
@@ -6,7 +6,9 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"os"
+	"strings"
 )
 
 // MaybeSetupDb is called by db.go and runs setupDb only in devsetup builds.
@@ -31,7 +33,7 @@ func setupDb(tx *sql.Tx) error {
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS accounts (
 		       uuid BINARY(16) NOT NULL PRIMARY KEY,
-		       username VARCHAR(16) UNIQUE NOT NULL,
+		       username VARCHAR(16) COLLATE utf8mb4_general_ci UNIQUE NOT NULL,
 		       hash BINARY(32) NOT NULL,
 		       salt BINARY(16) NOT NULL,
 		       registered TIMESTAMP NOT NULL,
@@ -44,6 +46,9 @@ func setupDb(tx *sql.Tx) error {
 		       googleId VARCHAR(32) UNIQUE DEFAULT NULL
 	       )`,
 		`CREATE INDEX IF NOT EXISTS accountsByActivity ON accounts (lastActivity)`,
+		`CREATE INDEX IF NOT EXISTS accountsByRegistered ON accounts (registered)`,
+		`CREATE INDEX IF NOT EXISTS accountsByTrainerIds ON accounts (trainerId, secretId)`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS profilePrivate TINYINT(1) NOT NULL DEFAULT 0`,
 
 		`CREATE TABLE IF NOT EXISTS sessions (
 		       token BINARY(32) NOT NULL PRIMARY KEY,
@@ -72,12 +77,18 @@ func setupDb(tx *sql.Tx) error {
 		       goldenVouchers INT(11) NOT NULL DEFAULT 0,
 		       CONSTRAINT accountStats_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
 	       )`,
+		`CREATE INDEX IF NOT EXISTS accountStatsByHighestEndlessWave ON accountStats (highestEndlessWave)`,
+		`CREATE INDEX IF NOT EXISTS accountStatsBySessionsWon ON accountStats (sessionsWon)`,
+		`CREATE INDEX IF NOT EXISTS accountStatsByPokemonSeen ON accountStats (pokemonSeen)`,
+		`CREATE INDEX IF NOT EXISTS accountStatsByPokemonCaught ON accountStats (pokemonCaught)`,
+		`CREATE INDEX IF NOT EXISTS accountStatsByPokemonHatched ON accountStats (pokemonHatched)`,
 
 		`CREATE TABLE IF NOT EXISTS dailyRuns (
 		       date DATE NOT NULL PRIMARY KEY,
 		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL
 	       )`,
 		`CREATE INDEX IF NOT EXISTS dailyRunsByDateAndSeed ON dailyRuns (date, seed)`,
+		`CREATE INDEX IF NOT EXISTS dailyRunsBySeed ON dailyRuns (seed)`,
 
 		`CREATE TABLE IF NOT EXISTS dailyRunCompletions (
 		       uuid BINARY(16) NOT NULL,
@@ -101,6 +112,46 @@ func setupDb(tx *sql.Tx) error {
 		       CONSTRAINT accountDailyRuns_ibfk_2 FOREIGN KEY (date) REFERENCES dailyRuns (date) ON DELETE NO ACTION ON UPDATE NO ACTION
 	       )`,
 		`CREATE INDEX IF NOT EXISTS accountDailyRunsByDate ON accountDailyRuns (date)`,
+		`CREATE INDEX IF NOT EXISTS accountDailyRunsByDateAndScore ON accountDailyRuns (date, score DESC, timestamp)`,
+
+		`CREATE TABLE IF NOT EXISTS dailySeedSecrets (
+		       version INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       secret VARBINARY(64) NOT NULL,
+		       activeFrom DATE NOT NULL,
+		       created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS dailyRunOverrides (
+		       date DATE NOT NULL PRIMARY KEY,
+		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+		       name VARCHAR(64) NOT NULL DEFAULT '',
+		       createdBy BINARY(16) DEFAULT NULL,
+		       created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS eventRuns (
+		       id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       name VARCHAR(64) NOT NULL,
+		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+		       starts TIMESTAMP NOT NULL,
+		       ends TIMESTAMP NOT NULL,
+		       createdBy BINARY(16) DEFAULT NULL,
+		       created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	       )`,
+		`CREATE INDEX IF NOT EXISTS eventRunsByWindow ON eventRuns (starts, ends)`,
+		`CREATE INDEX IF NOT EXISTS eventRunsBySeed ON eventRuns (seed)`,
+
+		`CREATE TABLE IF NOT EXISTS accountEventRuns (
+		       uuid BINARY(16) NOT NULL,
+		       event INT NOT NULL,
+		       score INT(11) NOT NULL DEFAULT 0,
+		       wave INT(11) NOT NULL DEFAULT 0,
+		       timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       PRIMARY KEY (uuid, event),
+		       CONSTRAINT accountEventRuns_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE,
+		       CONSTRAINT accountEventRuns_ibfk_2 FOREIGN KEY (event) REFERENCES eventRuns (id) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS accountEventRunsByEvent ON accountEventRuns (event, score)`,
 
 		`CREATE TABLE IF NOT EXISTS sessionSaveData (
 		       uuid BINARY(16),
@@ -116,6 +167,192 @@ func setupDb(tx *sql.Tx) error {
 		       clientSessionId VARCHAR(32) NOT NULL,
 		       FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
 	       )`,
+
+		`CREATE TABLE IF NOT EXISTS roles (
+		       name VARCHAR(32) NOT NULL PRIMARY KEY
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS permissions (
+		       name VARCHAR(64) NOT NULL PRIMARY KEY
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS rolePermissions (
+		       role VARCHAR(32) NOT NULL,
+		       permission VARCHAR(64) NOT NULL,
+		       PRIMARY KEY (role, permission),
+		       CONSTRAINT rolePermissions_ibfk_1 FOREIGN KEY (role) REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+		       CONSTRAINT rolePermissions_ibfk_2 FOREIGN KEY (permission) REFERENCES permissions (name) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS accountRoles (
+		       uuid BINARY(16) NOT NULL,
+		       role VARCHAR(32) NOT NULL,
+		       source VARCHAR(16) NOT NULL DEFAULT 'manual',
+		       granted TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       PRIMARY KEY (uuid, role),
+		       CONSTRAINT accountRoles_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE,
+		       CONSTRAINT accountRoles_ibfk_2 FOREIGN KEY (role) REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS accountRolesBySource ON accountRoles (source)`,
+
+		`CREATE TABLE IF NOT EXISTS adminAuditLog (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       actor BINARY(16) NOT NULL,
+		       actorUsername VARCHAR(16) DEFAULT NULL,
+		       action VARCHAR(64) NOT NULL,
+		       target BINARY(16) DEFAULT NULL,
+		       targetUsername VARCHAR(16) DEFAULT NULL,
+		       oldValue TEXT NOT NULL,
+		       newValue TEXT NOT NULL,
+		       ip VARCHAR(45) NOT NULL DEFAULT '',
+		       timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	       )`,
+		`CREATE INDEX IF NOT EXISTS adminAuditLogByActor ON adminAuditLog (actor, id)`,
+		`CREATE INDEX IF NOT EXISTS adminAuditLogByTarget ON adminAuditLog (target, id)`,
+		`CREATE INDEX IF NOT EXISTS adminAuditLogByAction ON adminAuditLog (action, id)`,
+		`CREATE INDEX IF NOT EXISTS adminAuditLogByTimestamp ON adminAuditLog (timestamp)`,
+
+		`CREATE TABLE IF NOT EXISTS accountBans (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       issuer BINARY(16) DEFAULT NULL,
+		       reason TEXT NOT NULL,
+		       evidence VARCHAR(512) NOT NULL DEFAULT '',
+		       issued TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       expires TIMESTAMP NULL DEFAULT NULL,
+		       lifted TIMESTAMP NULL DEFAULT NULL,
+		       liftedBy BINARY(16) DEFAULT NULL,
+		       liftReason TEXT NOT NULL DEFAULT '',
+		       appeal TEXT DEFAULT NULL,
+		       appealed TIMESTAMP NULL DEFAULT NULL,
+		       CONSTRAINT accountBans_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS accountBansByUuid ON accountBans (uuid, lifted)`,
+		`CREATE INDEX IF NOT EXISTS accountBansByExpiry ON accountBans (lifted, expires)`,
+		// accounts banned before bans were recorded have the flag set but no bans;
+		// accounts with any ban, even an expired one not lifted yet, are left alone
+		`INSERT INTO accountBans (uuid, reason, issued) SELECT a.uuid, 'banned before ban history was kept', UTC_TIMESTAMP() FROM accounts a WHERE a.banned = 1 AND NOT EXISTS (SELECT 1 FROM accountBans b WHERE b.uuid = a.uuid)`,
+
+		`CREATE TABLE IF NOT EXISTS accountDeletions (
+		       uuid BINARY(16) NOT NULL PRIMARY KEY,
+		       requested TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       scheduled TIMESTAMP NOT NULL,
+		       CONSTRAINT accountDeletions_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS accountDeletionsByScheduled ON accountDeletions (scheduled)`,
+
+		`CREATE TABLE IF NOT EXISTS usernameHistory (
+		       id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       oldUsername VARCHAR(16) NOT NULL,
+		       newUsername VARCHAR(16) NOT NULL,
+		       changed TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       CONSTRAINT usernameHistory_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS usernameHistoryByUuid ON usernameHistory (uuid, changed)`,
+		`CREATE INDEX IF NOT EXISTS usernameHistoryByOldUsername ON usernameHistory (oldUsername)`,
+
+		`CREATE TABLE IF NOT EXISTS flaggedRuns (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+		       date DATE NULL DEFAULT NULL,
+		       event INT NULL DEFAULT NULL,
+		       score INT(11) NOT NULL DEFAULT 0,
+		       wave INT(11) NOT NULL DEFAULT 0,
+		       reasons TEXT NOT NULL,
+		       session LONGBLOB DEFAULT NULL,
+		       status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+		       flagged TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       cleared TIMESTAMP NULL DEFAULT NULL,
+		       reviewedBy BINARY(16) DEFAULT NULL,
+		       reviewed TIMESTAMP NULL DEFAULT NULL,
+		       UNIQUE KEY flaggedRunsByRun (uuid, seed),
+		       CONSTRAINT flaggedRuns_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE,
+		       CONSTRAINT flaggedRuns_ibfk_2 FOREIGN KEY (event) REFERENCES eventRuns (id) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS flaggedRunsByStatus ON flaggedRuns (status, id)`,
+		`CREATE INDEX IF NOT EXISTS flaggedRunsByDate ON flaggedRuns (uuid, date)`,
+
+		`CREATE TABLE IF NOT EXISTS sessionLedger (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+		       wave INT(11) NOT NULL DEFAULT 0,
+		       money INT(11) NOT NULL DEFAULT 0,
+		       score INT(11) NOT NULL DEFAULT 0,
+		       victoryCount INT(11) NOT NULL DEFAULT 0,
+		       playTime INT(11) NOT NULL DEFAULT 0,
+		       party TEXT NOT NULL,
+		       modifiers INT(11) NOT NULL DEFAULT 0,
+		       final BOOLEAN NOT NULL DEFAULT FALSE,
+		       recorded TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       CONSTRAINT sessionLedger_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS sessionLedgerByRun ON sessionLedger (uuid, seed, id)`,
+		`CREATE INDEX IF NOT EXISTS sessionLedgerByRecorded ON sessionLedger (recorded)`,
+
+		`CREATE TABLE IF NOT EXISTS systemLedger (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       changes MEDIUMTEXT NOT NULL,
+		       anomalies TEXT NOT NULL,
+		       flagged BOOLEAN NOT NULL DEFAULT FALSE,
+		       recorded TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       CONSTRAINT systemLedger_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS systemLedgerByUuid ON systemLedger (uuid, id)`,
+		`CREATE INDEX IF NOT EXISTS systemLedgerByRecorded ON systemLedger (recorded)`,
+
+		`CREATE TABLE IF NOT EXISTS sessionHistory (
+		       id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		       uuid BINARY(16) NOT NULL,
+		       seed CHAR(24) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+		       result TINYINT NOT NULL,
+		       gameMode TINYINT NOT NULL,
+		       score INT(11) NOT NULL DEFAULT 0,
+		       wave INT(11) NOT NULL DEFAULT 0,
+		       playTime INT(11) NOT NULL DEFAULT 0,
+		       data LONGBLOB NOT NULL,
+		       archived TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		       CONSTRAINT sessionHistory_ibfk_1 FOREIGN KEY (uuid) REFERENCES accounts (uuid) ON DELETE CASCADE ON UPDATE CASCADE
+	       )`,
+		`CREATE INDEX IF NOT EXISTS sessionHistoryByUuid ON sessionHistory (uuid, id)`,
+		`CREATE INDEX IF NOT EXISTS sessionHistoryByArchived ON sessionHistory (archived)`,
+
+		`CREATE TABLE IF NOT EXISTS statsSnapshots (
+		       date DATE NOT NULL PRIMARY KEY,
+		       peakPlayerCount INT(11) NOT NULL DEFAULT 0,
+		       battleCount INT(11) NOT NULL DEFAULT 0,
+		       classicSessionCount INT(11) NOT NULL DEFAULT 0,
+		       accountCount INT(11) NOT NULL DEFAULT 0,
+		       registrations INT(11) NOT NULL DEFAULT 0,
+		       dailyRunPlayers INT(11) NOT NULL DEFAULT 0,
+		       wins INT(11) NOT NULL DEFAULT 0
+	       )`,
+
+		`CREATE TABLE IF NOT EXISTS dexStats (
+		       species INT NOT NULL PRIMARY KEY,
+		       seen INT(11) NOT NULL DEFAULT 0,
+		       caught INT(11) NOT NULL DEFAULT 0,
+		       hatched INT(11) NOT NULL DEFAULT 0,
+		       shiny INT(11) NOT NULL DEFAULT 0,
+		       variant2 INT(11) NOT NULL DEFAULT 0,
+		       variant3 INT(11) NOT NULL DEFAULT 0,
+		       classicWins INT(11) NOT NULL DEFAULT 0,
+		       passive INT(11) NOT NULL DEFAULT 0
+	       )`,
+		`CREATE TABLE IF NOT EXISTS dexStatsRun (
+		       id TINYINT NOT NULL PRIMARY KEY,
+		       players INT(11) NOT NULL DEFAULT 0,
+		       computed TIMESTAMP NOT NULL
+	       )`,
+
+		`INSERT IGNORE INTO roles (name) VALUES ('admin'), ('moderator'), ('helper')`,
+		`INSERT IGNORE INTO permissions (name) VALUES ('account.link'), ('account.search'), ('account.ban'), ('save.restore'), ('leaderboard.moderate'), ('role.manage'), ('audit.view'), ('account.delete'), ('daily.manage')`,
+		`INSERT IGNORE INTO rolePermissions (role, permission) SELECT 'admin', name FROM permissions`,
+		`INSERT IGNORE INTO rolePermissions (role, permission) VALUES ('moderator', 'account.link'), ('moderator', 'account.search'), ('moderator', 'account.ban'), ('moderator', 'leaderboard.moderate')`,
+		`INSERT IGNORE INTO rolePermissions (role, permission) VALUES ('helper', 'account.link'), ('helper', 'account.search')`,
 	}
 
 	// Conditionally add systemSaveData table if AWS_ENDPOINT_URL_S3 is not set
@@ -135,5 +372,55 @@ func setupDb(tx *sql.Tx) error {
 		}
 	}
 
+	return migrateUsernameCollation(tx)
+}
+
+// migrateUsernameCollation makes usernames of databases created before they
+// were case-insensitive unique regardless of case. Accounts whose usernames
+// only differ in case would break the unique key, so while there are any the
+// collation is left alone and they are reported instead.
+func migrateUsernameCollation(tx *sql.Tx) error {
+	var collation string
+	err := tx.QueryRow("SELECT COALESCE(COLLATION_NAME, '') FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'accounts' AND COLUMN_NAME = 'username'").Scan(&collation)
+	if err != nil {
+		return fmt.Errorf("failed to check username collation: %w", err)
+	}
+
+	if collation == "utf8mb4_general_ci" {
+		return nil
+	}
+
+	results, err := tx.Query("SELECT GROUP_CONCAT(username SEPARATOR ', ') FROM accounts GROUP BY CONVERT(username USING utf8mb4) COLLATE utf8mb4_general_ci HAVING COUNT(*) > 1")
+	if err != nil {
+		return fmt.Errorf("failed to check username case collisions: %w", err)
+	}
+
+	var collisions []string
+	for results.Next() {
+		var usernames string
+		err = results.Scan(&usernames)
+		if err != nil {
+			results.Close()
+			return err
+		}
+
+		collisions = append(collisions, usernames)
+	}
+
+	results.Close()
+	if err = results.Err(); err != nil {
+		return err
+	}
+
+	if len(collisions) > 0 {
+		log.Printf("usernames are still case-sensitive: rename one account of each of these to fix it: %s", strings.Join(collisions, "; "))
+		return nil
+	}
+
+	_, err = tx.Exec("ALTER TABLE accounts MODIFY username VARCHAR(16) COLLATE utf8mb4_general_ci NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to change username collation: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,111 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// FetchActiveAccountsAfter returns up to limit uuids of unbanned accounts
+// active since the given time, ordered by uuid and starting after the given
+// uuid. Pass an empty uuid to start from the beginning.
+func (s *store) FetchActiveAccountsAfter(after []byte, since time.Time, limit int) ([][]byte, error) {
+	var uuids [][]byte
+
+	results, err := handle.Query("SELECT uuid FROM accounts WHERE uuid > ? AND lastActivity >= ? AND banned = 0 ORDER BY uuid LIMIT ?", after, since, limit)
+	if err != nil {
+		return uuids, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var uuid []byte
+		err = results.Scan(&uuid)
+		if err != nil {
+			return uuids, err
+		}
+
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids, results.Err()
+}
+
+// StoreDexStats replaces the results of the last dex stats run.
+func (s *store) StoreDexStats(players int, counts []defs.DexSpeciesCounts) error {
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM dexStats")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, species := range counts {
+		_, err = tx.Exec("INSERT INTO dexStats (species, seen, caught, hatched, shiny, variant2, variant3, classicWins, passive) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", species.Species, species.Seen, species.Caught, species.Hatched, species.Shiny, species.Variant2, species.Variant3, species.ClassicWins, species.Passive)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("REPLACE INTO dexStatsRun (id, players, computed) VALUES (1, ?, UTC_TIMESTAMP())", players)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FetchDexStats returns the results of the last dex stats run, ordered by
+// species.
+func (s *store) FetchDexStats() (int, string, []defs.DexSpeciesCounts, error) {
+	var players int
+	var computed string
+	err := handle.QueryRow("SELECT players, computed FROM dexStatsRun WHERE id = 1").Scan(&players, &computed)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	results, err := handle.Query("SELECT species, seen, caught, hatched, shiny, variant2, variant3, classicWins, passive FROM dexStats ORDER BY species")
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	defer results.Close()
+
+	var counts []defs.DexSpeciesCounts
+	for results.Next() {
+		var species defs.DexSpeciesCounts
+		err = results.Scan(&species.Species, &species.Seen, &species.Caught, &species.Hatched, &species.Shiny, &species.Variant2, &species.Variant3, &species.ClassicWins, &species.Passive)
+		if err != nil {
+			return 0, "", nil, err
+		}
+
+		counts = append(counts, species)
+	}
+
+	return players, computed, counts, results.Err()
+}
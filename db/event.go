@@ -0,0 +1,136 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"math"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func (s *store) AddEventRun(name, seed string, starts, ends time.Time, createdBy []byte) (int64, error) {
+	result, err := handle.Exec("INSERT INTO eventRuns (name, seed, starts, ends, createdBy, created) VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP())", name, seed, starts, ends, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *store) RemoveEventRun(id int64) (bool, error) {
+	result, err := handle.Exec("DELETE FROM eventRuns WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *store) FetchEventRun(id int64) (defs.EventRun, error) {
+	var event defs.EventRun
+	err := handle.QueryRow("SELECT id, name, seed, starts, ends FROM eventRuns WHERE id = ?", id).Scan(&event.Id, &event.Name, &event.Seed, &event.Starts, &event.Ends)
+	if err != nil {
+		return event, err
+	}
+
+	return event, nil
+}
+
+// FetchEventRuns returns the events overlapping the window between from and
+// to, ordered by start.
+func (s *store) FetchEventRuns(from, to time.Time) ([]defs.EventRun, error) {
+	events := []defs.EventRun{}
+
+	results, err := handle.Query("SELECT id, name, seed, starts, ends FROM eventRuns WHERE ends >= ? AND starts <= ? ORDER BY starts, id", from, to)
+	if err != nil {
+		return events, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var event defs.EventRun
+		err = results.Scan(&event.Id, &event.Name, &event.Seed, &event.Starts, &event.Ends)
+		if err != nil {
+			return events, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, results.Err()
+}
+
+// FetchActiveEventRunBySeed returns the id of the event currently running with seed.
+func (s *store) FetchActiveEventRunBySeed(seed string) (int64, error) {
+	var id int64
+	err := handle.QueryRow("SELECT id FROM eventRuns WHERE seed = ? AND starts <= UTC_TIMESTAMP() AND ends > UTC_TIMESTAMP() ORDER BY starts DESC LIMIT 1", seed).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *store) AddOrUpdateAccountEventRun(uuid []byte, event int64, score int, wave int) error {
+	_, err := handle.Exec("INSERT INTO accountEventRuns (uuid, event, score, wave, timestamp) VALUES (?, ?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE timestamp = IF(score < ?, UTC_TIMESTAMP(), timestamp), score = GREATEST(score, ?), wave = GREATEST(wave, ?)", uuid, event, score, wave, score, score, wave)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) FetchEventRankings(event int64, page int) ([]defs.DailyRanking, error) {
+	rankings := []defs.DailyRanking{}
+
+	results, err := handle.Query("SELECT RANK() OVER (ORDER BY aer.score DESC, aer.timestamp), a.username, aer.score, aer.wave FROM accountEventRuns aer JOIN accounts a ON aer.uuid = a.uuid WHERE aer.event = ? AND a.banned = 0 AND "+unreviewedEventRun+" LIMIT 10 OFFSET ?", event, (page-1)*10)
+	if err != nil {
+		return rankings, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var ranking defs.DailyRanking
+		err = results.Scan(&ranking.Rank, &ranking.Username, &ranking.Score, &ranking.Wave)
+		if err != nil {
+			return rankings, err
+		}
+
+		rankings = append(rankings, ranking)
+	}
+
+	return rankings, results.Err()
+}
+
+func (s *store) FetchEventRankingPageCount(event int64) (int, error) {
+	var recordCount int
+	err := handle.QueryRow("SELECT COUNT(a.username) FROM accountEventRuns aer JOIN accounts a ON aer.uuid = a.uuid WHERE aer.event = ? AND a.banned = 0 AND "+unreviewedEventRun, event).Scan(&recordCount)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(math.Ceil(float64(recordCount) / 10)), nil
+}
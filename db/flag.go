@@ -0,0 +1,197 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const flagColumns = "f.uuid, f.id, a.username, f.seed, COALESCE(f.date, ''), COALESCE(f.event, 0), f.score, f.wave, f.reasons, f.status, f.flagged, COALESCE(f.cleared, ''), COALESCE(r.username, ''), COALESCE(f.reviewed, '')"
+
+const flagJoins = "flaggedRuns f JOIN accounts a ON a.uuid = f.uuid LEFT JOIN accounts r ON r.uuid = f.reviewedBy"
+
+// unreviewedDailyRun and unreviewedEventRun hold a run back from the
+// leaderboards while its flag is awaiting review or has been rejected.
+const (
+	unreviewedDailyRun = "NOT EXISTS (SELECT 1 FROM flaggedRuns f WHERE f.uuid = adr.uuid AND f.date = adr.date AND f.status <> 'approved')"
+	unreviewedEventRun = "NOT EXISTS (SELECT 1 FROM flaggedRuns f WHERE f.uuid = aer.uuid AND f.event = aer.event AND f.status <> 'approved')"
+)
+
+type flagScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRunFlag scans the flag columns, followed by any extra columns into
+// extra.
+func scanRunFlag(row flagScanner, extra ...any) (defs.RunFlag, error) {
+	var flag defs.RunFlag
+	var reasons string
+	err := row.Scan(append([]any{&flag.Uuid, &flag.Id, &flag.Username, &flag.Seed, &flag.Date, &flag.Event, &flag.Score, &flag.Wave, &reasons, &flag.Status, &flag.Flagged, &flag.Cleared, &flag.ReviewedBy, &flag.Reviewed}, extra...)...)
+	if err != nil {
+		return flag, err
+	}
+
+	flag.Reasons = strings.Split(reasons, "\n")
+
+	return flag, nil
+}
+
+// FetchRunFlag returns the flag raised on the account's run of seed.
+func (s *store) FetchRunFlag(uuid []byte, seed string) (defs.RunFlag, error) {
+	return scanRunFlag(handle.QueryRow("SELECT "+flagColumns+" FROM "+flagJoins+" WHERE f.uuid = ? AND f.seed = ?", uuid, seed))
+}
+
+// FetchRunFlagById returns a flag along with the last save of the run, if
+// one was kept.
+func (s *store) FetchRunFlagById(id int64) (defs.RunFlag, error) {
+	var session []byte
+	flag, err := scanRunFlag(handle.QueryRow("SELECT "+flagColumns+", f.session FROM "+flagJoins+" WHERE f.id = ?", id), &session)
+	if err != nil {
+		return flag, err
+	}
+
+	if session != nil {
+		flag.Session = new(defs.SessionSaveData)
+		err = decodeGob(session, flag.Session)
+		if err != nil {
+			return flag, err
+		}
+	}
+
+	return flag, nil
+}
+
+// FetchRunFlags returns a page of flags, oldest first so the review queue is
+// worked through in order. status limits the result when not empty.
+func (s *store) FetchRunFlags(status defs.RunFlagStatus, page, pageSize int) (defs.RunFlagPage, error) {
+	response := defs.RunFlagPage{Flags: []defs.RunFlag{}, Page: page}
+
+	where := " WHERE 1 = 1"
+	var args []any
+	if status != "" {
+		where += " AND f.status = ?"
+		args = append(args, status)
+	}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM flaggedRuns f"+where, args...).Scan(&count)
+	if err != nil {
+		return response, err
+	}
+
+	response.PageCount = int(math.Ceil(float64(count) / float64(pageSize)))
+
+	results, err := handle.Query("SELECT "+flagColumns+" FROM "+flagJoins+where+" ORDER BY f.id LIMIT ? OFFSET ?", append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return response, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		flag, err := scanRunFlag(results)
+		if err != nil {
+			return response, err
+		}
+
+		response.Flags = append(response.Flags, flag)
+	}
+
+	return response, results.Err()
+}
+
+// StoreRunFlag flags the account's run of seed with reasons, replacing the
+// reasons and snapshot of an existing flag and returning it to review.
+func (s *store) StoreRunFlag(uuid []byte, seed string, score, wave int, reasons []string, session defs.SessionSaveData) error {
+	encoded, err := encodeGob(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Exec("INSERT INTO flaggedRuns (uuid, seed, score, wave, reasons, session, flagged) VALUES (?, ?, ?, ?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE score = GREATEST(score, VALUES(score)), wave = GREATEST(wave, VALUES(wave)), reasons = VALUES(reasons), session = VALUES(session), status = 'pending', reviewedBy = NULL, reviewed = NULL", uuid, seed, score, wave, strings.Join(reasons, "\n"), encoded)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// QuarantineRunFlag records the result of a flagged run when it is cleared,
+// along with the daily run date or event it would have been recorded to.
+func (s *store) QuarantineRunFlag(uuid []byte, seed, date string, event int64, score, wave int, session defs.SessionSaveData) error {
+	encoded, err := encodeGob(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Exec("UPDATE flaggedRuns SET date = NULLIF(?, ''), event = NULLIF(?, 0), score = GREATEST(score, ?), wave = GREATEST(wave, ?), session = ?, cleared = UTC_TIMESTAMP() WHERE uuid = ? AND seed = ?", date, event, score, wave, encoded, uuid, seed)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReviewRunFlag marks a pending flag as approved or rejected. Approving a
+// cleared run publishes its result to the leaderboard it was held back
+// from; rejecting one removes the account's entry from that leaderboard. It
+// reports false if the flag was not pending.
+func (s *store) ReviewRunFlag(id int64, status defs.RunFlagStatus, reviewer []byte) (bool, error) {
+	tx, err := handle.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec("UPDATE flaggedRuns SET status = ?, reviewedBy = ?, reviewed = UTC_TIMESTAMP() WHERE id = ? AND status = 'pending'", status, reviewer, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	var queries []string
+	switch status {
+	case defs.RunFlagApproved:
+		queries = []string{
+			"INSERT INTO accountDailyRuns (uuid, date, score, wave, timestamp) SELECT f.uuid, f.date, f.score, f.wave, f.cleared FROM flaggedRuns f WHERE f.id = ? AND f.date IS NOT NULL ON DUPLICATE KEY UPDATE timestamp = IF(score < VALUES(score), VALUES(timestamp), timestamp), score = GREATEST(score, VALUES(score)), wave = GREATEST(wave, VALUES(wave))",
+			"INSERT INTO accountEventRuns (uuid, event, score, wave, timestamp) SELECT f.uuid, f.event, f.score, f.wave, f.cleared FROM flaggedRuns f WHERE f.id = ? AND f.event IS NOT NULL ON DUPLICATE KEY UPDATE timestamp = IF(score < VALUES(score), VALUES(timestamp), timestamp), score = GREATEST(score, VALUES(score)), wave = GREATEST(wave, VALUES(wave))",
+		}
+	case defs.RunFlagRejected:
+		queries = []string{
+			"DELETE adr FROM accountDailyRuns adr JOIN flaggedRuns f ON f.uuid = adr.uuid AND f.date = adr.date WHERE f.id = ?",
+			"DELETE aer FROM accountEventRuns aer JOIN flaggedRuns f ON f.uuid = aer.uuid AND f.event = aer.event WHERE f.id = ?",
+		}
+	}
+
+	for _, query := range queries {
+		_, err = tx.Exec(query, id)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
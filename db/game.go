@@ -17,6 +17,10 @@
 
 package db
 
+import (
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
 func (s *store) FetchPlayerCount() (int, error) {
 	var playerCount int
 	err := handle.QueryRow("SELECT COUNT(*) FROM accounts WHERE lastActivity > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 5 MINUTE)").Scan(&playerCount)
@@ -46,3 +50,84 @@ func (s *store) FetchClassicSessionCount() (int, error) {
 
 	return classicSessionCount, nil
 }
+
+func (s *store) FetchAccountCount() (int, error) {
+	var accountCount int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accounts WHERE banned = 0").Scan(&accountCount)
+	if err != nil {
+		return 0, err
+	}
+
+	return accountCount, nil
+}
+
+// FetchRegistrationCount returns the number of accounts registered on the
+// given UTC day.
+func (s *store) FetchRegistrationCount(date string) (int, error) {
+	var registrations int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accounts WHERE registered >= ? AND registered < DATE_ADD(?, INTERVAL 1 DAY)", date, date).Scan(&registrations)
+	if err != nil {
+		return 0, err
+	}
+
+	return registrations, nil
+}
+
+// FetchDailyRunPlayerCount returns the number of accounts that played the
+// daily run of the given date.
+func (s *store) FetchDailyRunPlayerCount(date string) (int, error) {
+	var players int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accountDailyRuns adr JOIN accounts a ON a.uuid = adr.uuid WHERE adr.date = ? AND a.banned = 0", date).Scan(&players)
+	if err != nil {
+		return 0, err
+	}
+
+	return players, nil
+}
+
+// FetchWinCount returns the number of runs won on the given UTC day.
+func (s *store) FetchWinCount(date string) (int, error) {
+	var wins int
+	err := handle.QueryRow("SELECT COUNT(*) FROM sessionHistory WHERE result = ? AND archived >= ? AND archived < DATE_ADD(?, INTERVAL 1 DAY)", defs.SessionHistoryResultWin, date, date).Scan(&wins)
+	if err != nil {
+		return 0, err
+	}
+
+	return wins, nil
+}
+
+// StoreStatsSnapshot records the metrics of a day, replacing an earlier
+// snapshot of the same day. The peak player count only ever goes up.
+func (s *store) StoreStatsSnapshot(snapshot defs.StatsSnapshot) error {
+	_, err := handle.Exec(`INSERT INTO statsSnapshots (date, peakPlayerCount, battleCount, classicSessionCount, accountCount, registrations, dailyRunPlayers, wins) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE peakPlayerCount = GREATEST(peakPlayerCount, VALUES(peakPlayerCount)), battleCount = VALUES(battleCount), classicSessionCount = VALUES(classicSessionCount), accountCount = VALUES(accountCount), registrations = VALUES(registrations), dailyRunPlayers = VALUES(dailyRunPlayers), wins = VALUES(wins)`,
+		snapshot.Date, snapshot.PeakPlayerCount, snapshot.BattleCount, snapshot.ClassicSessionCount, snapshot.AccountCount, snapshot.Registrations, snapshot.DailyRunPlayers, snapshot.Wins)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FetchStatsSnapshots returns the snapshots of the days between from and to,
+// oldest first.
+func (s *store) FetchStatsSnapshots(from, to string) ([]defs.StatsSnapshot, error) {
+	results, err := handle.Query("SELECT date, peakPlayerCount, battleCount, classicSessionCount, accountCount, registrations, dailyRunPlayers, wins FROM statsSnapshots WHERE date BETWEEN ? AND ? ORDER BY date", from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	snapshots := []defs.StatsSnapshot{}
+	for results.Next() {
+		var snapshot defs.StatsSnapshot
+		err = results.Scan(&snapshot.Date, &snapshot.PeakPlayerCount, &snapshot.BattleCount, &snapshot.ClassicSessionCount, &snapshot.AccountCount, &snapshot.Registrations, &snapshot.DailyRunPlayers, &snapshot.Wins)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, results.Err()
+}
@@ -0,0 +1,115 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"math"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// AddSessionHistory archives a finished run, dropping the account's oldest
+// archived runs beyond the latest maxRuns.
+func (s *store) AddSessionHistory(uuid []byte, history defs.SessionHistoryData, maxRuns int) error {
+	data, err := encodeGob(history)
+	if err != nil {
+		return err
+	}
+
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO sessionHistory (uuid, seed, result, gameMode, score, wave, playTime, data, archived) VALUES (?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())", uuid, history.Seed, history.Result, history.GameMode, history.Score, history.WaveIndex, history.PlayTime, data)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM sessionHistory WHERE uuid = ? AND id NOT IN (SELECT id FROM (SELECT id FROM sessionHistory WHERE uuid = ? ORDER BY id DESC LIMIT ?) latest)", uuid, uuid, maxRuns)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FetchSessionHistory returns a page of the account's archived runs, newest
+// first.
+func (s *store) FetchSessionHistory(uuid []byte, page, pageSize int) (defs.SessionHistoryPage, error) {
+	history := defs.SessionHistoryPage{Runs: []defs.SessionHistoryEntry{}, Page: page}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM sessionHistory WHERE uuid = ?", uuid).Scan(&count)
+	if err != nil {
+		return history, err
+	}
+
+	history.PageCount = int(math.Ceil(float64(count) / float64(pageSize)))
+
+	results, err := handle.Query("SELECT id, seed, result, gameMode, score, wave, playTime, archived FROM sessionHistory WHERE uuid = ? ORDER BY id DESC LIMIT ? OFFSET ?", uuid, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return history, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var entry defs.SessionHistoryEntry
+		err = results.Scan(&entry.Id, &entry.Seed, &entry.Result, &entry.GameMode, &entry.Score, &entry.WaveIndex, &entry.PlayTime, &entry.Archived)
+		if err != nil {
+			return history, err
+		}
+
+		history.Runs = append(history.Runs, entry)
+	}
+
+	return history, results.Err()
+}
+
+// FetchSessionHistoryRun returns one of the account's archived runs.
+func (s *store) FetchSessionHistoryRun(uuid []byte, id int64) (defs.SessionHistoryData, error) {
+	var history defs.SessionHistoryData
+
+	var data []byte
+	err := handle.QueryRow("SELECT data FROM sessionHistory WHERE uuid = ? AND id = ?", uuid, id).Scan(&data)
+	if err != nil {
+		return history, err
+	}
+
+	err = decodeGob(data, &history)
+	if err != nil {
+		return history, err
+	}
+
+	return history, nil
+}
+
+// PruneSessionHistory deletes runs archived before cutoff and returns the
+// number of runs deleted.
+func (s *store) PruneSessionHistory(cutoff time.Time) (int64, error) {
+	result, err := handle.Exec("DELETE FROM sessionHistory WHERE archived < ?", cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,213 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+const ledgerColumns = "wave, money, score, victoryCount, playTime, party, modifiers, final, recorded"
+
+type ledgerScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLedgerEntry(row ledgerScanner) (defs.SessionLedgerEntry, error) {
+	var entry defs.SessionLedgerEntry
+	var party []byte
+	err := row.Scan(&entry.Wave, &entry.Money, &entry.Score, &entry.VictoryCount, &entry.PlayTime, &party, &entry.Modifiers, &entry.Final, &entry.Recorded)
+	if err != nil {
+		return entry, err
+	}
+
+	err = json.Unmarshal(party, &entry.Party)
+	if err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+}
+
+// AddSessionLedgerEntry appends an entry to the ledger of the account's run
+// of seed.
+func (s *store) AddSessionLedgerEntry(uuid []byte, seed string, entry defs.SessionLedgerEntry) error {
+	party, err := json.Marshal(entry.Party)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Exec("INSERT INTO sessionLedger (uuid, seed, wave, money, score, victoryCount, playTime, party, modifiers, final, recorded) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())", uuid, seed, entry.Wave, entry.Money, entry.Score, entry.VictoryCount, entry.PlayTime, party, entry.Modifiers, entry.Final)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FetchLastSessionLedgerEntry returns the latest entry in the ledger of the
+// account's run of seed.
+func (s *store) FetchLastSessionLedgerEntry(uuid []byte, seed string) (defs.SessionLedgerEntry, error) {
+	return scanLedgerEntry(handle.QueryRow("SELECT "+ledgerColumns+" FROM sessionLedger WHERE uuid = ? AND seed = ? ORDER BY id DESC LIMIT 1", uuid, seed))
+}
+
+// FetchSessionLedger returns the ledger of the account's run of seed, oldest
+// entry first.
+func (s *store) FetchSessionLedger(uuid []byte, seed string) ([]defs.SessionLedgerEntry, error) {
+	entries := []defs.SessionLedgerEntry{}
+
+	results, err := handle.Query("SELECT "+ledgerColumns+" FROM sessionLedger WHERE uuid = ? AND seed = ? ORDER BY id", uuid, seed)
+	if err != nil {
+		return entries, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		entry, err := scanLedgerEntry(results)
+		if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, results.Err()
+}
+
+// PruneSessionLedger deletes ledger entries recorded before cutoff, keeping
+// the ledgers of flagged runs for review. It returns the number of entries
+// deleted.
+func (s *store) PruneSessionLedger(cutoff time.Time) (int64, error) {
+	result, err := handle.Exec("DELETE l FROM sessionLedger l WHERE l.recorded < ? AND NOT EXISTS (SELECT 1 FROM flaggedRuns f WHERE f.uuid = l.uuid AND f.seed = l.seed)", cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// systemLedgerChanges is the part of a system ledger entry stored in the
+// changes column.
+type systemLedgerChanges struct {
+	EggsAdded      []defs.EggData `json:"eggsAdded"`
+	EggsRemoved    []int          `json:"eggsRemoved"`
+	Vouchers       map[string]int `json:"vouchers"`
+	VoucherUnlocks []string       `json:"voucherUnlocks"`
+	Notes          []string       `json:"notes"`
+}
+
+// AddSystemLedgerEntry appends an entry to the account's system ledger.
+// Entries with anomalies are flagged.
+func (s *store) AddSystemLedgerEntry(uuid []byte, entry defs.SystemLedgerEntry) error {
+	changes, err := json.Marshal(systemLedgerChanges{
+		EggsAdded:      entry.EggsAdded,
+		EggsRemoved:    entry.EggsRemoved,
+		Vouchers:       entry.Vouchers,
+		VoucherUnlocks: entry.VoucherUnlocks,
+		Notes:          entry.Notes,
+	})
+	if err != nil {
+		return err
+	}
+
+	anomalies, err := json.Marshal(entry.Anomalies)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Exec("INSERT INTO systemLedger (uuid, changes, anomalies, flagged, recorded) VALUES (?, ?, ?, ?, UTC_TIMESTAMP())", uuid, changes, anomalies, len(entry.Anomalies) > 0)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FetchSystemLedger returns a page of the account's system ledger, newest
+// entry first. If flagged is set, only entries with anomalies are returned.
+func (s *store) FetchSystemLedger(uuid []byte, flagged bool, page, pageSize int) (defs.SystemLedgerPage, error) {
+	ledger := defs.SystemLedgerPage{Entries: []defs.SystemLedgerEntry{}, Page: page}
+
+	condition := "uuid = ?"
+	if flagged {
+		condition += " AND flagged"
+	}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM systemLedger WHERE "+condition, uuid).Scan(&count)
+	if err != nil {
+		return ledger, err
+	}
+
+	ledger.PageCount = int(math.Ceil(float64(count) / float64(pageSize)))
+
+	results, err := handle.Query("SELECT id, changes, anomalies, recorded FROM systemLedger WHERE "+condition+" ORDER BY id DESC LIMIT ? OFFSET ?", uuid, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return ledger, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var entry defs.SystemLedgerEntry
+		var changes, anomalies []byte
+		err = results.Scan(&entry.Id, &changes, &anomalies, &entry.Recorded)
+		if err != nil {
+			return ledger, err
+		}
+
+		var stored systemLedgerChanges
+		err = json.Unmarshal(changes, &stored)
+		if err != nil {
+			return ledger, err
+		}
+
+		err = json.Unmarshal(anomalies, &entry.Anomalies)
+		if err != nil {
+			return ledger, err
+		}
+
+		entry.EggsAdded, entry.EggsRemoved, entry.Vouchers, entry.VoucherUnlocks = stored.EggsAdded, stored.EggsRemoved, stored.Vouchers, stored.VoucherUnlocks
+
+		// entries recorded before notes were kept have none
+		entry.Notes = stored.Notes
+		if entry.Notes == nil {
+			entry.Notes = []string{}
+		}
+
+		ledger.Entries = append(ledger.Entries, entry)
+	}
+
+	return ledger, results.Err()
+}
+
+// PruneSystemLedger deletes system ledger entries recorded before cutoff,
+// keeping flagged entries for review. It returns the number of entries
+// deleted.
+func (s *store) PruneSystemLedger(cutoff time.Time) (int64, error) {
+	result, err := handle.Exec("DELETE FROM systemLedger WHERE recorded < ? AND NOT flagged", cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
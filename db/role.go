@@ -0,0 +1,188 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+func (s *store) FetchAccountPermissions(uuid []byte) ([]defs.Permission, error) {
+	var permissions []defs.Permission
+
+	results, err := handle.Query("SELECT DISTINCT rp.permission FROM accountRoles ar JOIN rolePermissions rp ON rp.role = ar.role WHERE ar.uuid = ?", uuid)
+	if err != nil {
+		return permissions, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var permission defs.Permission
+		err = results.Scan(&permission)
+		if err != nil {
+			return permissions, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, results.Err()
+}
+
+func (s *store) HasAccountPermission(uuid []byte, permission defs.Permission) (bool, error) {
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accountRoles ar JOIN rolePermissions rp ON rp.role = ar.role WHERE ar.uuid = ? AND rp.permission = ?", uuid, permission).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *store) FetchAccountRoles(uuid []byte) ([]defs.AccountRole, error) {
+	var roles []defs.AccountRole
+
+	results, err := handle.Query("SELECT role, source FROM accountRoles WHERE uuid = ? ORDER BY role", uuid)
+	if err != nil {
+		return roles, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var role defs.AccountRole
+		err = results.Scan(&role.Role, &role.Source)
+		if err != nil {
+			return roles, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, results.Err()
+}
+
+func (s *store) FetchRoles() ([]defs.Role, error) {
+	var roles []defs.Role
+
+	results, err := handle.Query("SELECT r.name, rp.permission FROM roles r LEFT JOIN rolePermissions rp ON rp.role = r.name ORDER BY r.name, rp.permission")
+	if err != nil {
+		return roles, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var name string
+		var permission *string
+		err = results.Scan(&name, &permission)
+		if err != nil {
+			return roles, err
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, defs.Role{Name: name, Permissions: []defs.Permission{}})
+		}
+
+		if permission != nil {
+			roles[len(roles)-1].Permissions = append(roles[len(roles)-1].Permissions, defs.Permission(*permission))
+		}
+	}
+
+	return roles, results.Err()
+}
+
+func (s *store) RoleExists(role string) (bool, error) {
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM roles WHERE name = ?", role).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *store) GrantAccountRole(uuid []byte, role string, source defs.RoleSource) error {
+	_, err := handle.Exec("INSERT INTO accountRoles (uuid, role, source, granted) VALUES (?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE source = IF(source = ?, source, VALUES(source))", uuid, role, source, defs.RoleSourceManual)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) RevokeAccountRole(uuid []byte, role string) error {
+	_, err := handle.Exec("DELETE FROM accountRoles WHERE uuid = ? AND role = ?", uuid, role)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReplaceAccountRolesFromSource swaps every grant the account received from
+// source for roles. Grants from other sources are left untouched.
+func (s *store) ReplaceAccountRolesFromSource(uuid []byte, source defs.RoleSource, roles []string) error {
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM accountRoles WHERE uuid = ? AND source = ?", uuid, source)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, role := range roles {
+		_, err = tx.Exec("INSERT IGNORE INTO accountRoles (uuid, role, source, granted) SELECT ?, name, ?, UTC_TIMESTAMP() FROM roles WHERE name = ?", uuid, source, role)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FetchDiscordRoleHolders returns every account that holds a grant synced
+// from Discord, keyed by the account's uuid. Accounts that have since
+// unlinked Discord map to an empty id.
+func (s *store) FetchDiscordRoleHolders() (map[string]string, error) {
+	holders := make(map[string]string)
+
+	results, err := handle.Query("SELECT DISTINCT a.uuid, COALESCE(a.discordId, '') FROM accountRoles ar JOIN accounts a ON a.uuid = ar.uuid WHERE ar.source = ?", defs.RoleSourceDiscord)
+	if err != nil {
+		return holders, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var uuid []byte
+		var discordId string
+		err = results.Scan(&uuid, &discordId)
+		if err != nil {
+			return holders, err
+		}
+
+		holders[string(uuid)] = discordId
+	}
+
+	return holders, results.Err()
+}
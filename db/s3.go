@@ -59,3 +59,39 @@ func (s *store) StoreSystemSaveDataS3(uuid []byte, data defs.SystemSaveData) err
 
 	return nil
 }
+
+func (s *store) DeleteSystemSaveDataS3(uuid []byte) error {
+	username, err := s.FetchUsernameFromUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	return s.DeleteSystemSaveDataS3ByUsername(username)
+}
+
+func (s *store) DeleteSystemSaveDataS3ByUsername(username string) error {
+	_, err := s3client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(os.Getenv("S3_SYSTEM_BUCKET_NAME")),
+		Key:    aws.String(username),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) CopySystemSaveDataS3(fromUsername, toUsername string) error {
+	bucket := os.Getenv("S3_SYSTEM_BUCKET_NAME")
+
+	_, err := s3client.CopyObject(context.Background(), &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		CopySource: aws.String(bucket + "/" + fromUsername),
+		Key:        aws.String(toUsername),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
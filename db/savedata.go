@@ -120,14 +120,7 @@ func (s *store) ReadSessionSaveData(uuid []byte, slot int) (defs.SessionSaveData
 		return session, err
 	}
 
-	zr, err := zstd.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return session, err
-	}
-
-	defer zr.Close()
-
-	err = gob.NewDecoder(zr).Decode(&session)
+	err = decodeGob(data, &session)
 	if err != nil {
 		return session, err
 	}
@@ -146,29 +139,52 @@ func (s *store) GetLatestSessionSaveDataSlot(uuid []byte) (int, error) {
 }
 
 func (s *store) StoreSessionSaveData(uuid []byte, data defs.SessionSaveData, slot int) error {
+	encoded, err := encodeGob(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Exec("REPLACE INTO sessionSaveData (uuid, slot, data, timestamp) VALUES (?, ?, ?, UTC_TIMESTAMP())", uuid, slot, encoded)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// encodeGob gob encodes and compresses data the way saves are stored in the
+// database.
+func encodeGob(data any) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	zw, err := zstd.NewWriter(buf)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = gob.NewEncoder(zw).Encode(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = zw.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = handle.Exec("REPLACE INTO sessionSaveData (uuid, slot, data, timestamp) VALUES (?, ?, ?, UTC_TIMESTAMP())", uuid, slot, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// decodeGob decompresses and decodes data written by encodeGob into v.
+func decodeGob(data []byte, v any) error {
+	zr, err := zstd.NewReader(bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	defer zr.Close()
+
+	return gob.NewDecoder(zr).Decode(v)
 }
 
 func (s *store) DeleteSessionSaveData(uuid []byte, slot int) error {
@@ -0,0 +1,129 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *store) SearchAccounts(filter defs.AccountSearchFilter) (defs.AccountSearchPage, error) {
+	page := defs.AccountSearchPage{Accounts: []defs.AccountSearchResult{}, Page: filter.Page}
+
+	var conditions []string
+	var args []any
+
+	if filter.Username != "" {
+		// previous usernames match too, so renamed accounts can still be found
+		condition, arg := "%s LIKE ?", any("%"+likeEscaper.Replace(filter.Username)+"%")
+		switch filter.UsernameMatch {
+		case defs.UsernameMatchExact:
+			condition, arg = "%s = ?", filter.Username
+		case defs.UsernameMatchPrefix:
+			arg = likeEscaper.Replace(filter.Username) + "%"
+		}
+
+		conditions = append(conditions, "("+fmt.Sprintf(condition, "username")+" OR uuid IN (SELECT uuid FROM usernameHistory WHERE "+fmt.Sprintf(condition, "oldUsername")+"))")
+		args = append(args, arg, arg)
+	}
+	if filter.DiscordId != "" {
+		conditions = append(conditions, "discordId = ?")
+		args = append(args, filter.DiscordId)
+	}
+	if filter.GoogleId != "" {
+		conditions = append(conditions, "googleId = ?")
+		args = append(args, filter.GoogleId)
+	}
+	if filter.TrainerId != nil {
+		conditions = append(conditions, "trainerId = ?")
+		args = append(args, *filter.TrainerId)
+	}
+	if filter.SecretId != nil {
+		conditions = append(conditions, "secretId = ?")
+		args = append(args, *filter.SecretId)
+	}
+	if filter.Uuid != nil {
+		conditions = append(conditions, "uuid = ?")
+		args = append(args, filter.Uuid)
+	}
+	if filter.Banned != nil {
+		conditions = append(conditions, "banned = ?")
+		args = append(args, *filter.Banned)
+	}
+	if filter.RegisteredFrom != "" {
+		conditions = append(conditions, "registered >= ?")
+		args = append(args, filter.RegisteredFrom)
+	}
+	if filter.RegisteredTo != "" {
+		conditions = append(conditions, "registered < DATE_ADD(?, INTERVAL 1 DAY)")
+		args = append(args, filter.RegisteredTo)
+	}
+	if filter.ActiveFrom != "" {
+		conditions = append(conditions, "lastActivity >= ?")
+		args = append(args, filter.ActiveFrom)
+	}
+	if filter.ActiveTo != "" {
+		conditions = append(conditions, "lastActivity < DATE_ADD(?, INTERVAL 1 DAY)")
+		args = append(args, filter.ActiveTo)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	err := handle.QueryRow("SELECT COUNT(*) FROM accounts"+where, args...).Scan(&count)
+	if err != nil {
+		return page, err
+	}
+
+	page.PageCount = int(math.Ceil(float64(count) / float64(filter.PageSize)))
+
+	results, err := handle.Query("SELECT uuid, username, COALESCE(discordId, ''), COALESCE(googleId, ''), trainerId, secretId, banned, registered, COALESCE(lastActivity, ''), COALESCE((SELECT GROUP_CONCAT(oldUsername ORDER BY changed) FROM usernameHistory h WHERE h.uuid = accounts.uuid), '') FROM accounts"+where+" ORDER BY username LIMIT ? OFFSET ?", append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)...)
+	if err != nil {
+		return page, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var uuid []byte
+		var previousUsernames string
+		var result defs.AccountSearchResult
+		err = results.Scan(&uuid, &result.Username, &result.DiscordId, &result.GoogleId, &result.TrainerId, &result.SecretId, &result.Banned, &result.Registered, &result.LastActivity, &previousUsernames)
+		if err != nil {
+			return page, err
+		}
+
+		if previousUsernames != "" {
+			result.PreviousUsernames = strings.Split(previousUsernames, ",")
+		}
+
+		result.Uuid = hex.EncodeToString(uuid)
+		page.Accounts = append(page.Accounts, result)
+	}
+
+	return page, results.Err()
+}
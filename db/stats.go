@@ -0,0 +1,82 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pagefaultgames/rogueserver/defs"
+)
+
+// rankedStatColumn checks that stat can be ranked and is safe to splice into
+// a query.
+func rankedStatColumn(stat string) (string, error) {
+	if !slices.Contains(RankedStatColumns, stat) || !slices.Contains(AccountStatColumns, stat) {
+		return "", fmt.Errorf("unknown stat %q", stat)
+	}
+
+	return "s." + stat, nil
+}
+
+// FetchStatRankings ranks accounts by an accountStats column, leaving out
+// banned accounts and accounts that haven't scored on it yet. Equal values
+// share a rank and are listed by username.
+func (s *store) FetchStatRankings(stat string, limit, offset int) ([]defs.StatRanking, error) {
+	rankings := []defs.StatRanking{}
+
+	column, err := rankedStatColumn(stat)
+	if err != nil {
+		return rankings, err
+	}
+
+	results, err := handle.Query("SELECT RANK() OVER (ORDER BY "+column+" DESC), a.username, "+column+" FROM accountStats s JOIN accounts a ON a.uuid = s.uuid WHERE a.banned = 0 AND "+column+" > 0 ORDER BY "+column+" DESC, a.username LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return rankings, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var ranking defs.StatRanking
+		err = results.Scan(&ranking.Rank, &ranking.Username, &ranking.Value)
+		if err != nil {
+			return rankings, err
+		}
+
+		rankings = append(rankings, ranking)
+	}
+
+	return rankings, results.Err()
+}
+
+// FetchStatRankingCount returns the number of accounts ranked on stat.
+func (s *store) FetchStatRankingCount(stat string) (int, error) {
+	column, err := rankedStatColumn(stat)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = handle.QueryRow("SELECT COUNT(*) FROM accountStats s JOIN accounts a ON a.uuid = s.uuid WHERE a.banned = 0 AND " + column + " > 0").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
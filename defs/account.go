@@ -0,0 +1,51 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type AccountDetails struct {
+	Username     string `json:"username"`
+	Registered   string `json:"registered"`
+	LastLoggedIn string `json:"lastLoggedIn"`
+	LastActivity string `json:"lastActivity"`
+	Banned       bool   `json:"banned"`
+	TrainerId    int    `json:"trainerId"`
+	SecretId     int    `json:"secretId"`
+	DiscordId    string `json:"discordId"`
+	GoogleId     string `json:"googleId"`
+	Private      bool   `json:"private"` // profile hidden from other players
+}
+
+// Profile is the view of an account shown on its profile page. Stats holds
+// the accountStats columns; DailyWins counts the daily runs the account
+// topped.
+type Profile struct {
+	Username    string           `json:"username"`
+	Registered  string           `json:"registered"`
+	Private     bool             `json:"private"`
+	Stats       map[string]int   `json:"stats"`
+	ClassicWins int              `json:"classicWins"`
+	DailyRuns   int              `json:"dailyRuns"`
+	DailyWins   int              `json:"dailyWins"`
+	DailyBest   *AccountDailyRun `json:"dailyBest,omitempty"`
+}
+
+type UsernameChange struct {
+	OldUsername string `json:"oldUsername"`
+	NewUsername string `json:"newUsername"`
+	Changed     string `json:"changed"`
+}
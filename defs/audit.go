@@ -0,0 +1,70 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type AuditAction string
+
+const (
+	AuditActionDiscordLink   AuditAction = "account.discordLink"
+	AuditActionDiscordUnlink AuditAction = "account.discordUnlink"
+	AuditActionGoogleLink    AuditAction = "account.googleLink"
+	AuditActionGoogleUnlink  AuditAction = "account.googleUnlink"
+	AuditActionSearch        AuditAction = "account.search"
+	AuditActionRoleGrant     AuditAction = "role.grant"
+	AuditActionRoleRevoke    AuditAction = "role.revoke"
+	AuditActionBan           AuditAction = "account.ban"
+	AuditActionUnban         AuditAction = "account.unban"
+	AuditActionDelete        AuditAction = "account.delete"
+	AuditActionExport        AuditAction = "account.export"
+	AuditActionDailyOverride AuditAction = "daily.override"
+	AuditActionDailyRestore  AuditAction = "daily.restore"
+	AuditActionEventCreate   AuditAction = "daily.eventCreate"
+	AuditActionEventRemove   AuditAction = "daily.eventRemove"
+	AuditActionRotateSecret  AuditAction = "daily.rotateSecret"
+	AuditActionRunApprove    AuditAction = "run.approve"
+	AuditActionRunReject     AuditAction = "run.reject"
+)
+
+type AuditLogEntry struct {
+	Id        int64       `json:"id"`
+	Actor     string      `json:"actor"`
+	Action    AuditAction `json:"action"`
+	Target    string      `json:"target"`
+	Before    string      `json:"before"`
+	After     string      `json:"after"`
+	IP        string      `json:"ip"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// AuditLogFilter narrows an audit log query. Zero values match everything.
+// From and To are days, both inclusive.
+type AuditLogFilter struct {
+	Actor    []byte
+	Target   []byte
+	Action   AuditAction
+	From     string
+	To       string
+	Page     int
+	PageSize int
+}
+
+type AuditLogPage struct {
+	Entries   []AuditLogEntry `json:"entries"`
+	Page      int             `json:"page"`
+	PageCount int             `json:"pageCount"`
+}
@@ -0,0 +1,48 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type Ban struct {
+	Id         int64  `json:"id"`
+	Username   string `json:"username"`
+	Reason     string `json:"reason"`
+	Issuer     string `json:"issuer"` // empty when issued automatically by the server
+	Issued     string `json:"issued"`
+	Expires    string `json:"expires"` // empty for permanent bans
+	Evidence   string `json:"evidence"`
+	Lifted     string `json:"lifted"`
+	LiftedBy   string `json:"liftedBy"`
+	LiftReason string `json:"liftReason"`
+	Appeal     string `json:"appeal"`
+	Appealed   string `json:"appealed"`
+}
+
+// BanStatus is the player-facing view of an active ban.
+type BanStatus struct {
+	Reason    string `json:"reason"`
+	Issued    string `json:"issued"`
+	Expires   string `json:"expires"`
+	Appealed  bool   `json:"appealed"`
+	Automatic bool   `json:"automatic"`
+}
+
+type BanPage struct {
+	Bans      []Ban `json:"bans"`
+	Page      int   `json:"page"`
+	PageCount int   `json:"pageCount"`
+}
@@ -17,9 +17,107 @@
 
 package defs
 
+const (
+	RankingCategoryDaily = iota
+	RankingCategoryWeekly
+	RankingCategoryMonthly
+	RankingCategoryAllTime
+)
+
 type DailyRanking struct {
 	Rank     int    `json:"rank"`
 	Username string `json:"username"`
 	Score    int    `json:"score"`
 	Wave     int    `json:"wave"`
+	Self     bool   `json:"self,omitempty"`
+}
+
+// LeaderboardEntry is an unranked leaderboard row as cached in memory.
+type LeaderboardEntry struct {
+	Uuid      []byte
+	Username  string
+	Score     int
+	Wave      int
+	Timestamp string
+}
+
+// PlayerRanking is a player's place in a leaderboard along with the
+// entries around it, the player's own included.
+type PlayerRanking struct {
+	Rank     int            `json:"rank"`
+	Score    int            `json:"score"`
+	Wave     int            `json:"wave"`
+	Rankings []DailyRanking `json:"rankings"`
+}
+
+type AccountDailyRun struct {
+	Date      string `json:"date"`
+	Seed      string `json:"seed"`
+	Score     int    `json:"score"`
+	Wave      int    `json:"wave"`
+	Timestamp string `json:"timestamp"`
+}
+
+// DailyScheduleEntry is a single day of the daily run schedule. Seed is only
+// set once the day's run has started.
+type DailyScheduleEntry struct {
+	Date     string `json:"date"`
+	Seed     string `json:"seed,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Override bool   `json:"override"`
+}
+
+// EventRun is an admin-scheduled run with its own seed, window and
+// leaderboard. Seed is only exposed once the event has started.
+type EventRun struct {
+	Id     int64  `json:"id"`
+	Name   string `json:"name"`
+	Seed   string `json:"seed,omitempty"`
+	Starts string `json:"starts"`
+	Ends   string `json:"ends"`
+}
+
+type DailySchedule struct {
+	Timezone string               `json:"timezone"`
+	Rollover string               `json:"rollover"`
+	Today    string               `json:"today"`
+	Days     []DailyScheduleEntry `json:"days"`
+	Events   []EventRun           `json:"events"`
+}
+
+// DailySeedSecret is a versioned secret daily seeds are derived from. It is
+// used from ActiveFrom until a newer secret becomes active.
+type DailySeedSecret struct {
+	Version    int    `json:"version"`
+	ActiveFrom string `json:"activeFrom"`
+	Secret     []byte `json:"-"`
+}
+
+// DailyHistoryEntry summarises a past daily run. Winner is empty if nobody
+// played it.
+type DailyHistoryEntry struct {
+	Date         string `json:"date"`
+	Seed         string `json:"seed"`
+	Participants int    `json:"participants"`
+	Winner       string `json:"winner,omitempty"`
+	WinnerScore  int    `json:"winnerScore,omitempty"`
+}
+
+// DailyRankingsPage is a page of a daily run or event leaderboard.
+type DailyRankingsPage struct {
+	Rankings  []DailyRanking `json:"rankings"`
+	Page      int            `json:"page"`
+	PageCount int            `json:"pageCount"`
+}
+
+// DailySeed is the seed of the daily run of a date.
+type DailySeed struct {
+	Date string `json:"date"`
+	Seed string `json:"seed"`
+}
+
+type DailyHistoryPage struct {
+	Runs      []DailyHistoryEntry `json:"runs"`
+	Page      int                 `json:"page"`
+	PageCount int                 `json:"pageCount"`
 }
@@ -0,0 +1,55 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type RunFlagStatus string
+
+const (
+	RunFlagPending  RunFlagStatus = "pending"
+	RunFlagApproved RunFlagStatus = "approved"
+	RunFlagRejected RunFlagStatus = "rejected"
+)
+
+// RunFlag is a daily or event run held back from the leaderboards because
+// it failed one or more run checks. Date or Event is set once the run has
+// been cleared, depending on which leaderboard it would have been recorded
+// to. Session is the last save of the run, only filled in when inspecting a
+// single flag.
+type RunFlag struct {
+	Uuid       []byte           `json:"-"`
+	Id         int64            `json:"id"`
+	Username   string           `json:"username"`
+	Seed       string           `json:"seed"`
+	Date       string           `json:"date,omitempty"`
+	Event      int64            `json:"event,omitempty"`
+	Score      int              `json:"score"`
+	Wave       int              `json:"wave"`
+	Reasons    []string         `json:"reasons"`
+	Status     RunFlagStatus    `json:"status"`
+	Flagged    string           `json:"flagged"`
+	Cleared    string           `json:"cleared,omitempty"`
+	ReviewedBy string           `json:"reviewedBy,omitempty"`
+	Reviewed   string           `json:"reviewed,omitempty"`
+	Session    *SessionSaveData `json:"session,omitempty"`
+}
+
+type RunFlagPage struct {
+	Flags     []RunFlag `json:"flags"`
+	Page      int       `json:"page"`
+	PageCount int       `json:"pageCount"`
+}
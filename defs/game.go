@@ -18,6 +18,70 @@
 package defs
 
 type TitleStats struct {
-	PlayerCount int `json:"playerCount"`
-	BattleCount int `json:"battleCount"`
+	PlayerCount         int `json:"playerCount"`
+	BattleCount         int `json:"battleCount"`
+	ClassicSessionCount int `json:"classicSessionCount"`
+	AccountCount        int `json:"accountCount"`
+	DailyRunPlayers     int `json:"dailyRunPlayers"`
+}
+
+// StatsSnapshot holds the game metrics of a day. Totals are as of the last
+// snapshot taken that day.
+type StatsSnapshot struct {
+	Date                string `json:"date"`
+	PeakPlayerCount     int    `json:"peakPlayerCount"`
+	BattleCount         int    `json:"battleCount"`
+	ClassicSessionCount int    `json:"classicSessionCount"`
+	AccountCount        int    `json:"accountCount"`
+	Registrations       int    `json:"registrations"`
+	DailyRunPlayers     int    `json:"dailyRunPlayers"`
+	Wins                int    `json:"wins"`
+}
+
+// StatRanking is a place on an all-time stat leaderboard.
+type StatRanking struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Value    int    `json:"value"`
+}
+
+// StatRankingsPage is a page of an all-time stat leaderboard.
+type StatRankingsPage struct {
+	Rankings  []StatRanking `json:"rankings"`
+	Page      int           `json:"page"`
+	PageCount int           `json:"pageCount"`
+}
+
+// DexSpeciesCounts holds how many of the players counted by a dex stats run
+// have reached each milestone with a species.
+type DexSpeciesCounts struct {
+	Species     int
+	Seen        int
+	Caught      int
+	Hatched     int
+	Shiny       int
+	Variant2    int
+	Variant3    int
+	ClassicWins int
+	Passive     int
+}
+
+// DexSpeciesStats holds the percentage of players that have reached each
+// milestone with a species.
+type DexSpeciesStats struct {
+	Species    int     `json:"species"`
+	Seen       float64 `json:"seen"`
+	Caught     float64 `json:"caught"`
+	Hatched    float64 `json:"hatched"`
+	Shiny      float64 `json:"shiny"`
+	Variant2   float64 `json:"variant2"`
+	Variant3   float64 `json:"variant3"`
+	ClassicWin float64 `json:"classicWin"`
+	Passive    float64 `json:"passive"`
+}
+
+type DexStats struct {
+	Players  int               `json:"players"`
+	Computed string            `json:"computed"`
+	Species  []DexSpeciesStats `json:"species"`
 }
@@ -0,0 +1,37 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+// SessionHistoryEntry summarizes an archived run for the history list; the
+// full SessionHistoryData is fetched by Id.
+type SessionHistoryEntry struct {
+	Id        int64                `json:"id"`
+	Seed      string               `json:"seed"`
+	Result    SessionHistoryResult `json:"sessionHistoryResult"`
+	GameMode  GameMode             `json:"gameMode"`
+	Score     int                  `json:"score"`
+	WaveIndex int                  `json:"waveIndex"`
+	PlayTime  int                  `json:"playTime"`
+	Archived  string               `json:"archived"`
+}
+
+type SessionHistoryPage struct {
+	Runs      []SessionHistoryEntry `json:"runs"`
+	Page      int                   `json:"page"`
+	PageCount int                   `json:"pageCount"`
+}
@@ -0,0 +1,58 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+// SessionLedgerEntry holds the key fields of a session save, recorded each
+// time a run is saved so its progress can be checked and replayed. Final is
+// set on the entry recorded when the run is cleared.
+type SessionLedgerEntry struct {
+	Wave         int             `json:"wave"`
+	Money        int             `json:"money"`
+	Score        int             `json:"score"`
+	VictoryCount int             `json:"victoryCount"`
+	PlayTime     int             `json:"playTime"`
+	Party        []LedgerPokemon `json:"party"`
+	Modifiers    int             `json:"modifiers"` // total stack count of the player's modifiers
+	Final        bool            `json:"final"`
+	Recorded     string          `json:"recorded"`
+}
+
+type LedgerPokemon struct {
+	Species int `json:"species"`
+	Level   int `json:"level"`
+}
+
+// SystemLedgerEntry records how a system save update changed the account's
+// eggs and vouchers, along with anything about the change that the game could
+// not have produced.
+type SystemLedgerEntry struct {
+	Id             int64          `json:"id"`
+	EggsAdded      []EggData      `json:"eggsAdded"`
+	EggsRemoved    []int          `json:"eggsRemoved"`    // ids of eggs hatched since the last update
+	Vouchers       map[string]int `json:"vouchers"`       // change in each voucher count
+	VoucherUnlocks []string       `json:"voucherUnlocks"` // vouchers newly unlocked
+	Anomalies      []string       `json:"anomalies"`
+	Notes          []string       `json:"notes"` // findings of advisory checks, which don't flag the entry
+	Recorded       string         `json:"recorded"`
+}
+
+type SystemLedgerPage struct {
+	Entries   []SystemLedgerEntry `json:"entries"`
+	Page      int                 `json:"page"`
+	PageCount int                 `json:"pageCount"`
+}
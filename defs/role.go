@@ -0,0 +1,51 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type Permission string
+
+const (
+	PermissionAccountLink         Permission = "account.link"
+	PermissionAccountSearch       Permission = "account.search"
+	PermissionAccountBan          Permission = "account.ban"
+	PermissionAccountDelete       Permission = "account.delete"
+	PermissionSaveRestore         Permission = "save.restore"
+	PermissionLeaderboardModerate Permission = "leaderboard.moderate"
+	PermissionRoleManage          Permission = "role.manage"
+	PermissionAuditView           Permission = "audit.view"
+	PermissionDailyManage         Permission = "daily.manage"
+)
+
+// RoleSource records where an account's role grant came from, so that
+// synced grants can be replaced without touching manual ones.
+type RoleSource string
+
+const (
+	RoleSourceManual  RoleSource = "manual"
+	RoleSourceDiscord RoleSource = "discord"
+)
+
+type Role struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type AccountRole struct {
+	Role   string     `json:"role"`
+	Source RoleSource `json:"source"`
+}
@@ -182,3 +182,9 @@ type SessionHistoryData struct {
 }
 
 type SessionHistoryResult int
+
+const (
+	SessionHistoryResultWin SessionHistoryResult = iota + 1
+	SessionHistoryResultLoss
+	SessionHistoryResultAbandon
+)
@@ -0,0 +1,64 @@
+/*
+	Copyright (C) 2024 - 2025  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package defs
+
+type UsernameMatch string
+
+const (
+	UsernameMatchExact    UsernameMatch = "exact"
+	UsernameMatchPrefix   UsernameMatch = "prefix"
+	UsernameMatchContains UsernameMatch = "contains"
+)
+
+// AccountSearchFilter narrows an admin account search. Zero values match
+// everything; all set fields must match.
+type AccountSearchFilter struct {
+	Username       string
+	UsernameMatch  UsernameMatch
+	DiscordId      string
+	GoogleId       string
+	TrainerId      *int
+	SecretId       *int
+	Uuid           []byte
+	Banned         *bool
+	RegisteredFrom string
+	RegisteredTo   string
+	ActiveFrom     string
+	ActiveTo       string
+	Page           int
+	PageSize       int
+}
+
+type AccountSearchResult struct {
+	Uuid              string   `json:"uuid"`
+	Username          string   `json:"username"`
+	DiscordId         string   `json:"discordId"`
+	GoogleId          string   `json:"googleId"`
+	TrainerId         int      `json:"trainerId"`
+	SecretId          int      `json:"secretId"`
+	Banned            bool     `json:"banned"`
+	Registered        string   `json:"registered"`
+	LastActivity      string   `json:"lastActivity"`
+	PreviousUsernames []string `json:"previousUsernames,omitempty"`
+}
+
+type AccountSearchPage struct {
+	Accounts  []AccountSearchResult `json:"accounts"`
+	Page      int                   `json:"page"`
+	PageCount int                   `json:"pageCount"`
+}
@@ -24,10 +24,14 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
+	_ "time/tzdata" // daily timezone may be set in containers without zoneinfo
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/pagefaultgames/rogueserver/api"
 	"github.com/pagefaultgames/rogueserver/api/account"
+	"github.com/pagefaultgames/rogueserver/api/daily"
+	"github.com/pagefaultgames/rogueserver/api/savedata"
 	"github.com/pagefaultgames/rogueserver/db"
 )
 
@@ -58,6 +62,24 @@ func main() {
 
 	discordbottoken := getEnv("discordbottoken", "")
 	discordguildid := getEnv("discordguildid", "")
+	discordrolemap := getEnv("discordrolemap", "Dev:admin,Division Heads:admin,Helper:helper")
+
+	accountdeletiongrace := getEnv("accountdeletiongrace", "720h")
+
+	dailytimezone := getEnv("dailytimezone", "UTC")
+	dailyrollover := getEnv("dailyrollover", "00:00")
+	dailysecrets := getEnv("dailysecrets", "")
+	dailyweekstart := getEnv("dailyweekstart", "sunday")
+	rankingsmaxpagesize := getEnv("rankingsmaxpagesize", "50")
+
+	historymaxruns := getEnv("historymaxruns", "100")
+	historyretention := getEnv("historyretention", "2160h")
+
+	bodylimits := getEnv("bodylimits", "")
+	trustedproxies := getEnv("trustedproxies", "0")
+
+	usernamecooldown := getEnv("usernamecooldown", "720h")
+	usernamedenylist := getEnv("usernamedenylist", "admin,administrator,moderator,staff,support,official,pagefault,pokerogue,rogueserver")
 
 	account.GameURL = gameurl
 
@@ -71,12 +93,78 @@ func main() {
 	account.DiscordSession, _ = discordgo.New("Bot " + discordbottoken)
 	account.DiscordGuildID = discordguildid
 
+	discordRoleMap, err := account.ParseDiscordRoleMap(discordrolemap)
+	if err != nil {
+		log.Fatalf("failed to parse discord role map: %s", err)
+	}
+
+	account.DiscordRoleMap = discordRoleMap
+
+	account.DeletionGracePeriod, err = time.ParseDuration(accountdeletiongrace)
+	if err != nil {
+		log.Fatalf("failed to parse account deletion grace period: %s", err)
+	}
+
+	account.UsernameChangeCooldown, err = time.ParseDuration(usernamecooldown)
+	if err != nil {
+		log.Fatalf("failed to parse username change cooldown: %s", err)
+	}
+
+	account.UsernameDenyList = account.ParseUsernameDenyList(usernamedenylist)
+
+	daily.Location, err = time.LoadLocation(dailytimezone)
+	if err != nil {
+		log.Fatalf("failed to load daily timezone: %s", err)
+	}
+
+	daily.Rollover, err = daily.ParseRollover(dailyrollover)
+	if err != nil {
+		log.Fatalf("failed to parse daily rollover: %s", err)
+	}
+
+	daily.ConfigSecrets, err = daily.ParseSecrets(dailysecrets)
+	if err != nil {
+		log.Fatalf("failed to parse daily seed secrets: %s", err)
+	}
+
+	daily.WeekStart, err = daily.ParseWeekday(dailyweekstart)
+	if err != nil {
+		log.Fatalf("failed to parse daily week start: %s", err)
+	}
+
+	daily.RankingsMaxPageSize, err = strconv.Atoi(rankingsmaxpagesize)
+	if err != nil {
+		log.Fatalf("failed to parse rankings max page size: %s", err)
+	}
+
+	savedata.HistoryMaxRuns, err = strconv.Atoi(historymaxruns)
+	if err != nil {
+		log.Fatalf("failed to parse history max runs: %s", err)
+	}
+
+	savedata.HistoryRetention, err = time.ParseDuration(historyretention)
+	if err != nil {
+		log.Fatalf("failed to parse history retention: %s", err)
+	}
+
+	bodyLimits, err := api.ParseBodyLimits(bodylimits)
+	if err != nil {
+		log.Fatalf("failed to parse body limits: %s", err)
+	}
+
+	api.SetBodySizes(bodyLimits)
+
+	api.TrustedProxies, err = strconv.Atoi(trustedproxies)
+	if err != nil || api.TrustedProxies < 0 {
+		log.Fatalf("failed to parse trusted proxies: %s", trustedproxies)
+	}
+
 	// register gob types
 	gob.Register([]interface{}{})
 	gob.Register(map[string]interface{}{})
 
 	// get database connection
-	err := db.Init(dbuser, dbpass, dbproto, dbaddr, dbname)
+	err = db.Init(dbuser, dbpass, dbproto, dbaddr, dbname)
 	if err != nil {
 		log.Fatalf("failed to initialize database: %s", err)
 	}
@@ -133,7 +221,7 @@ func createListener(proto, addr string) (net.Listener, error) {
 func prodHandler(router *http.ServeMux, clienturl string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST")
+		w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, DELETE")
 		w.Header().Set("Access-Control-Allow-Origin", clienturl)
 
 		if r.Method == "OPTIONS" {